@@ -3,6 +3,7 @@ module github.com/Juli3nnicolas/bipper
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/faiface/beep v1.0.2
 	github.com/mum4k/termdash v0.12.0
 	gopkg.in/yaml.v2 v2.3.0
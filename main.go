@@ -1,13 +1,15 @@
 package main
 
-import "github.com/Juli3nnicolas/bipper/pkg/ui"
+import (
+	"fmt"
+	"os"
 
-func main() {
-	const bipFile string = "bip.mp3"
-	const endBipFile string = "end_bip.mp3"
-	const docFile string = "example.yaml"
+	"github.com/Juli3nnicolas/bipper/pkg/cli"
+)
 
-	tui := ui.TermDashUI{}
-	tui.Init(bipFile, endBipFile)
-	tui.Run()
-}
\ No newline at end of file
+func main() {
+	if err := cli.Run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -2,22 +2,79 @@ package bipper
 
 import (
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/history"
 	"github.com/Juli3nnicolas/bipper/pkg/sound"
 )
 
 type BipperOutput struct {
 	Msg            chan string
+	Warning        chan string
 	Section        chan document.Section
 	RawDoc         chan string
 	Remaining      chan time.Duration
 	TotalRemaining chan time.Duration
+	// Prestart receives the remaining time of the "get ready"
+	// countdown, zero is sent when the countdown is over
+	Prestart chan time.Duration
+	// Upcoming receives what follows a section right after
+	// the section is sent on Section
+	Upcoming chan Upcoming
+	// Plan receives the sections of every round when it starts
+	Plan chan []document.Section
+	// Paused receives true when the bipper pauses by itself,
+	// on waking up under the SuspendPause policy
+	Paused chan bool
 }
 
+// upcomingCount is the number of upcoming sections sent on Output.Upcoming
+const upcomingCount = 2
+
+// Upcoming describes where the current section stands in the session
+type Upcoming struct {
+	// Sections are the next sections, at most upcomingCount.
+	// The first sections of the next loop are included when looping.
+	Sections []document.Section
+	// Round is the current loop iteration, starting at 1
+	Round int
+	// Index is the position of the current section in the round, starting at 1
+	Index int
+	// Count is the number of sections in the round
+	Count int
+}
+
+// BipperInput channels are buffered, senders must not block
+// as they are not read while the bipper sends its output
 type BipperInput struct {
 	TogglePause chan bool
+	// SkipPrestart ends the "get ready" countdown, it is
+	// only read during the countdown
+	SkipPrestart chan bool
+	// Next ends the current section, the "get ready"
+	// countdown is skipped if it is playing
+	Next chan bool
+	// Previous goes back to the previous section of the round,
+	// the first section of a round is restarted
+	Previous chan bool
+	// Jump starts the section of the round at the given index
+	Jump chan int
+	// Reload replaces the document being played, it is
+	// only read once the "get ready" countdown is over
+	Reload chan Reload
+}
+
+// Reload is a new version of the document being played. The rest of
+// the current round is planned again from it, the sections already
+// played are kept as they were. The current section
+// keeps its elapsed time if the new plan still has a section of the
+// same name, the section taking its place is played otherwise.
+type Reload struct {
+	Raw string
+	Doc document.Document
 }
 
 type Bipper struct {
@@ -27,16 +84,57 @@ type Bipper struct {
 	endPlayer sound.Player
 	rawDoc    string
 	doc       document.Document
+	rand      *rand.Rand
+
+	// session records what is played until Finish is called
+	session  history.Session
+	finished bool
+	// position is where the session stands, started is
+	// false until the first section is played
+	position history.Checkpoint
+	started  bool
+	// resume is the checkpoint the session starts from, if any
+	resume        *history.Checkpoint
+	suspendPolicy SuspendPolicy
+	// done is closed by Stop, exited is closed when Bip returns
+	done   chan struct{}
+	exited chan struct{}
+	mu     sync.Mutex
 }
 
+// Init prepares the bipper to run the document stored in docFile
 func (o *Bipper) Init(bipFile, endBipFile, docFile string) (err error) {
-	o.Input.TogglePause = make(chan bool)
+	raw, doc, err := document.Read(docFile)
+	if err != nil {
+		return
+	}
+
+	o.InitDocument(bipFile, endBipFile, raw, doc)
+	return
+}
+
+// InitDocument prepares the bipper to run an already parsed document.
+// raw is the document's source, it is sent as is on Output.RawDoc.
+func (o *Bipper) InitDocument(bipFile, endBipFile, raw string, doc document.Document) {
+	o.Input.TogglePause = make(chan bool, 1)
+	o.Input.SkipPrestart = make(chan bool, 1)
+	o.Input.Next = make(chan bool, 1)
+	o.Input.Previous = make(chan bool, 1)
+	o.Input.Jump = make(chan int, 1)
+	o.Input.Reload = make(chan Reload, 1)
 
 	o.Output.Msg = make(chan string)
+	o.Output.Warning = make(chan string)
 	o.Output.Section = make(chan document.Section)
 	o.Output.RawDoc = make(chan string)
 	o.Output.Remaining = make(chan time.Duration)
 	o.Output.TotalRemaining = make(chan time.Duration)
+	o.Output.Prestart = make(chan time.Duration)
+	o.Output.Upcoming = make(chan Upcoming)
+	o.Output.Plan = make(chan []document.Section)
+	o.Output.Paused = make(chan bool)
+	o.done = make(chan struct{})
+	o.exited = make(chan struct{})
 
 	o.player = sound.NewPlayer()
 	o.player.Read(bipFile)
@@ -44,32 +142,351 @@ func (o *Bipper) Init(bipFile, endBipFile, docFile string) (err error) {
 	o.endPlayer = sound.NewPlayer()
 	o.endPlayer.Read(endBipFile)
 
-	o.rawDoc, o.doc, err = document.Read(docFile)
-	return
+	o.rawDoc = raw
+	o.doc = doc
+	o.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	o.session = history.Session{Hash: history.Hash(doc)}
+	o.position = history.Checkpoint{}
+	o.started = false
+	o.resume = nil
+	o.suspendPolicy = SuspendPause
+}
+
+// SetSuspendPolicy sets what is done when the computer
+// was suspended during a section, SuspendPause by default
+func (o *Bipper) SetSuspendPolicy(p SuspendPolicy) {
+	o.suspendPolicy = p
+}
+
+// Resume makes the session start from checkpoint c, it must be called
+// before Bip. The round is played as it was planned if the document
+// did not change, the current section otherwise keeps its remaining
+// time only if it has the same name in the new plan.
+func (o *Bipper) Resume(c history.Checkpoint) {
+	o.resume = &c
+}
+
+// Checkpoint returns where the session stands, ok is false if
+// no section was played yet or if the session is finished
+func (o *Bipper) Checkpoint() (c history.Checkpoint, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.started || o.finished {
+		return c, false
+	}
+	c = o.position
+	c.Document = o.session.Document
+	c.Hash = o.session.Hash
+	c.Raw = o.rawDoc
+	c.Saved = time.Now()
+	return c, true
+}
+
+// setPosition records the current section and its remaining time
+func (o *Bipper) setPosition(round, index int, remaining time.Duration, sections []document.Section) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.started = true
+	o.position.Round = round
+	o.position.Index = index
+	o.position.Remaining = remaining
+	o.position.Sections = sections
+}
+
+// setRemaining records the remaining time of the current section
+func (o *Bipper) setRemaining(remaining time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.position.Remaining = remaining
+}
+
+// SetPath sets the path of the document recorded in the session
+func (o *Bipper) SetPath(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.session.Document = path
 }
 
+// Finish ends the session and returns it, ok is false if it was already
+// finished. completed is true if the document was played to its end.
+func (o *Bipper) Finish(completed bool) (s history.Session, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.finished {
+		return s, false
+	}
+	o.finished = true
+	o.session.End = time.Now()
+	o.session.Completed = completed
+	return o.session, true
+}
+
+// record adds a played section to the session
+func (o *Bipper) record(s history.Section) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.finished {
+		o.session.Sections = append(o.session.Sections, s)
+	}
+}
+
+// Stop makes Bip return, the session is left as is. The outputs are
+// read until then so that Bip is not blocked sending a value that
+// nobody reads anymore. Bip must have been called.
+func (o *Bipper) Stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.stopped() {
+		return
+	}
+	close(o.done)
+	go o.drain()
+}
+
+// Ended returns true once Bip returned
+func (o *Bipper) Ended() bool {
+	select {
+	case <-o.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stopped returns true once Stop is called
+func (o *Bipper) stopped() bool {
+	select {
+	case <-o.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// drain reads the outputs until Bip returns
+func (o *Bipper) drain() {
+	for {
+		select {
+		case <-o.exited:
+			return
+		case <-o.Output.Msg:
+		case <-o.Output.Warning:
+		case <-o.Output.Section:
+		case <-o.Output.RawDoc:
+		case <-o.Output.Remaining:
+		case <-o.Output.TotalRemaining:
+		case <-o.Output.Prestart:
+		case <-o.Output.Upcoming:
+		case <-o.Output.Plan:
+		case <-o.Output.Paused:
+		}
+	}
+}
+
+// Seed makes the random durations and the
+// shuffled sections reproducible
+func (o *Bipper) Seed(seed int64) {
+	o.rand.Seed(seed)
+}
+
+// Bip plays the document until its end or until Stop is called
 func (o *Bipper) Bip() {
+	defer close(o.exited)
+
+	o.mu.Lock()
+	o.session.Start = time.Now()
+	o.mu.Unlock()
+
 	o.Output.RawDoc <- o.rawDoc
 
 	loop := true
-	tick := time.Tick(time.Second)
-	totalRemaining := o.doc.Total
+	first := true
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	tick := ticker.C
 	pause := false
 
-	for loop {
-		for _, section := range o.doc.Sections {
+	if o.doc.Prestart > 0 {
+		o.prestart(tick, &pause)
+	}
+	if o.stopped() {
+		return
+	}
+
+	// catchUp is the time missed while the computer was suspended
+	// that is left to skip in the next sections
+	var catchUp time.Duration
+	lastTick := time.Now().Round(0)
+
+	round := 1
+	if o.resume != nil {
+		round = o.resume.Round
+	}
+
+	// Random durations and section order are picked at each loop,
+	// the next loop is planned ahead to preview its first sections
+	plan := o.doc.Plan(o.rand)
+	for ; loop; round++ {
+		var next []document.Section
+		if o.doc.Loop {
+			next = o.doc.Plan(o.rand)
+		}
+
+		// Clock anchored sections are scheduled when the loop starts
+		sections, warnings := o.doc.Schedule(plan, time.Now())
+		// The first section played and the time already spent in it
+		var firstIndex int
+		var elapsed time.Duration
+		if o.resume != nil {
+			sections, firstIndex, elapsed = o.resumed(sections)
+			warnings = nil
+			o.resume = nil
+		}
+		if first {
+			for _, w := range warnings {
+				o.Output.Warning <- w
+			}
+			first = false
+		}
+		o.Output.Plan <- sections
+
+		// A looping document having nothing to play would loop forever
+		if len(sections) == 0 {
+			o.Output.Warning <- "Nothing left to play in the round, the session is over"
+			return
+		}
+
+		for i := firstIndex; i < len(sections); i++ {
+			// Sections that would have ended while the computer was
+			// suspended are skipped, the next one starts where it would be
+			if catchUp > 0 {
+				var skipped int
+				skipped, elapsed, catchUp = skipMissed(sections[i:], elapsed, catchUp)
+				for _, s := range sections[i : i+skipped] {
+					o.record(history.Section{Name: s.Name, Kind: s.Kind, Round: round, Planned: s.Duration, Skipped: true})
+				}
+				if i += skipped; i == len(sections) {
+					break
+				}
+			}
+			section := sections[i]
+
+			// The total is computed at each section as sections may be skipped
+			var totalRemaining time.Duration
+			for _, s := range sections[i:] {
+				totalRemaining += s.Duration
+			}
+			totalRemaining -= elapsed
+
 			o.Output.Msg <- fmt.Sprintf("\nRunning section %s lasting %v\n", section.Name, section.Duration)
 			o.Output.Section <- section
+			o.Output.Upcoming <- upcoming(sections[i+1:], next, round, i+1, len(sections))
 
-			var timer time.Time
+			o.setPosition(round, i, section.Duration-elapsed, sections)
+			timer := time.Time{}.Add(elapsed)
+			elapsed = 0
+			record := history.Section{Name: section.Name, Kind: section.Kind, Round: round, Planned: section.Duration}
+			// The times have no monotonic clock reading so that the time
+			// the computer is suspended counts in both Actual and Paused
+			start := time.Now().Round(0)
+			pausedAt := start
 
 			countingDown := true
 			for countingDown {
 				select {
+				case <-o.done:
+					return
+
 				case <-o.Input.TogglePause:
 					pause = !pause
+					if pause {
+						record.Pauses++
+						pausedAt = time.Now().Round(0)
+					} else {
+						record.Paused += time.Since(pausedAt)
+					}
+
+				case <-o.Input.Next:
+					record.Skipped = true
+					countingDown = false
+
+				case <-o.Input.Previous:
+					// i is incremented when the loop continues
+					if i > 0 {
+						i--
+					}
+					i--
+					record.Skipped = true
+					countingDown = false
+
+				case j := <-o.Input.Jump:
+					if j >= 0 && j < len(sections) {
+						i = j - 1
+						record.Skipped = true
+						countingDown = false
+					}
+
+				case reload := <-o.Input.Reload:
+					o.setDocument(reload)
+					next = nil
+					if o.doc.Loop {
+						next = o.doc.Plan(o.rand)
+					}
+					loop = o.doc.Loop
+					spent := timer.Sub(time.Time{})
+					var found bool
+					sections, found = rescheduled(o.doc, sections, o.doc.Plan(o.rand), i, time.Now(), spent)
+					o.Output.RawDoc <- o.rawDoc
+					o.Output.Plan <- sections
+
+					if !found {
+						// The section now at the place of the current one is played next
+						i--
+						record.Skipped = true
+						countingDown = false
+						break
+					}
+
+					section = sections[i]
+					record.Planned = section.Duration
+					totalRemaining = -spent
+					for _, s := range sections[i:] {
+						totalRemaining += s.Duration
+					}
+					o.setPosition(round, i, section.Duration-spent, sections)
+					o.Output.Section <- section
+					o.Output.Upcoming <- upcoming(sections[i+1:], next, round, i+1, len(sections))
 
 				case <-tick:
+					// The ticks stop while the computer is suspended
+					// but the wall clock keeps running
+					now := time.Now().Round(0)
+					missed := suspension(lastTick, now)
+					lastTick = now
+					suspended := !pause && missed > 0
+					if suspended {
+						if o.suspendPolicy == SuspendPause {
+							pause = true
+							record.Pauses++
+							// The suspended time counts in the pause
+							pausedAt = now.Add(-missed)
+							o.Output.Paused <- true
+							o.Output.Warning <- fmt.Sprintf("Paused after a suspension of %v", missed.Round(time.Second))
+							break
+						}
+
+						timer = timer.Add(missed)
+						totalRemaining -= missed
+						o.Output.Warning <- fmt.Sprintf("Caught up a suspension of %v", missed.Round(time.Second))
+					}
+
 					if !pause {
 						timer = timer.Add(time.Second)
 						duration := time.Time{}.Add(section.Duration)
@@ -81,12 +498,22 @@ func (o *Bipper) Bip() {
 						if remainingSec <= 0 {
 							o.Output.Remaining <- 0
 							o.Output.TotalRemaining <- totalRemaining
-							o.endPlayer.Play()
+							if suspended && o.suspendPolicy == SuspendAlarm {
+								o.ringMissedAlarm()
+								o.Output.Warning <- fmt.Sprintf("Missed the end of %s by %v", section.Name, (-remaining).Round(time.Second))
+							} else {
+								o.endPlayer.Play()
+							}
+							// The time missed after the end of the section is caught up by the next ones
+							if remaining < 0 {
+								catchUp = -remaining
+							}
 							o.Output.Msg <- fmt.Sprintf("Section %s is over\n", section.Name)
 							countingDown = false
 							break
 						}
 
+						o.setRemaining(remaining)
 						o.Output.Remaining <- remaining
 						if remainingSec >= 1.0 && remainingSec <= 3.0 {
 							o.player.Play()
@@ -97,13 +524,192 @@ func (o *Bipper) Bip() {
 					}
 				}
 			}
+
+			// A pause lasting after the section counts in the next one
+			if pause {
+				record.Paused += time.Since(pausedAt)
+			}
+			record.Actual = time.Since(start)
+			o.record(record)
 		}
+		plan = next
 		loop = o.doc.Loop
 	}
 }
 
+// setDocument replaces the document being played
+func (o *Bipper) setDocument(r Reload) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.rawDoc = r.Raw
+	o.doc = r.Doc
+	o.session.Hash = history.Hash(r.Doc)
+}
+
+// rescheduled returns the sections of a round reloaded while its i-th
+// section is played, spent is the time spent in it. The sections already
+// played are kept as they were, the rest of the round is planned again
+// from plan. found is false if plan has no section named as the current
+// one, the section taking its place is then at index i and scheduled
+// from now. The current section keeps index i otherwise and it is
+// scheduled from the time it started.
+func rescheduled(doc document.Document, sections, plan []document.Section, i int, now time.Time, spent time.Duration) (rescheduled []document.Section, found bool) {
+	played := sections[:i]
+	// p is the position of the current section in the plan,
+	// the waiting sections are not part of it
+	p := i
+	for _, s := range played {
+		if s.Wait {
+			p--
+		}
+	}
+
+	var rest []document.Section
+	if k := nearest(plan, sections[i].Name, p); k >= 0 {
+		// The current section has already waited for its At anchor
+		current := plan[k]
+		current.At = nil
+		rest, _ = doc.Schedule(append([]document.Section{current}, plan[k+1:]...), now.Add(-spent))
+		found = len(rest) > 0 && rest[0].Name == current.Name
+	}
+	if !found {
+		if p > len(plan) {
+			p = len(plan)
+		}
+		rest, _ = doc.Schedule(plan[p:], now)
+	}
+
+	rescheduled = append(rescheduled, played...)
+	return append(rescheduled, rest...), found
+}
+
+// nearest returns the index of the section named name that is the
+// closest to index i, -1 if there is none
+func nearest(sections []document.Section, name string, i int) int {
+	k := -1
+	for j, s := range sections {
+		if s.Name != name {
+			continue
+		}
+		if k < 0 || abs(j-i) < abs(k-i) {
+			k = j
+		}
+	}
+	return k
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// resumed returns the sections of the resumed round, the index of
+// the section to play first and the time already spent in it.
+// planned are the sections of the round planned from the document.
+func (o *Bipper) resumed(planned []document.Section) (sections []document.Section, index int, elapsed time.Duration) {
+	c := o.resume
+	if c.Hash == history.Hash(o.doc) && len(c.Sections) > 0 {
+		sections = c.Sections
+	} else {
+		sections = planned
+	}
+
+	index = c.Index
+	if index >= len(sections) {
+		index = len(sections) - 1
+	}
+	if index < 0 {
+		return sections, 0, 0
+	}
+
+	if current, ok := c.Section(); ok && current.Name == sections[index].Name {
+		if elapsed = sections[index].Duration - c.Remaining; elapsed < 0 {
+			elapsed = 0
+		}
+	}
+	return sections, index, elapsed
+}
+
+// upcoming returns the Upcoming value of the index-th section of a round,
+// rest holds the sections left in the round and next the next round's plan
+func upcoming(rest, next []document.Section, round, index, count int) Upcoming {
+	u := Upcoming{Round: round, Index: index, Count: count}
+	for _, sections := range [][]document.Section{rest, next} {
+		for _, s := range sections {
+			if len(u.Sections) == upcomingCount {
+				return u
+			}
+			u.Sections = append(u.Sections, s)
+		}
+	}
+
+	return u
+}
+
+// prestart plays the "get ready" countdown, it ends
+// early when a value is sent on Input.SkipPrestart
+func (o *Bipper) prestart(tick <-chan time.Time, pause *bool) {
+	// Drop skips requested before the countdown started
+	select {
+	case <-o.Input.SkipPrestart:
+	default:
+	}
+	defer func() {
+		// Going back or jumping during the countdown has no effect
+		select {
+		case <-o.Input.Previous:
+		default:
+		}
+		select {
+		case <-o.Input.Jump:
+		default:
+		}
+	}()
+
+	remaining := o.doc.Prestart
+	o.Output.Prestart <- remaining
+
+	for remaining > 0 {
+		select {
+		case <-o.done:
+			return
+
+		case <-o.Input.TogglePause:
+			*pause = !*pause
+
+		case <-o.Input.SkipPrestart:
+			remaining = 0
+
+		case <-o.Input.Next:
+			remaining = 0
+
+		case <-tick:
+			if *pause {
+				break
+			}
+
+			remaining -= time.Second
+			if remaining > 0 {
+				o.Output.Prestart <- remaining
+				if remaining <= 3*time.Second {
+					o.player.Play()
+				}
+			}
+		}
+	}
+
+	o.Output.Prestart <- 0
+	o.endPlayer.Play()
+}
+
 func (o *Bipper) Close() {
 	if o.player != nil {
 		o.player.Close()
 	}
+	if o.endPlayer != nil {
+		o.endPlayer.Close()
+	}
 }
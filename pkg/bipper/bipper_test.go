@@ -0,0 +1,166 @@
+package bipper
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+)
+
+// summarize lists sections as "name duration"
+func summarize(sections []document.Section) []string {
+	var l []string
+	for _, s := range sections {
+		l = append(l, fmt.Sprintf("%s %v", s.Name, s.Duration))
+	}
+	return l
+}
+
+// section returns a section lasting d minutes
+func section(name string, d int) document.Section {
+	return document.Section{Name: name, Duration: time.Duration(d) * time.Minute}
+}
+
+func TestNearest(t *testing.T) {
+	sections := []document.Section{section("Work", 1), section("Rest", 1), section("Work", 1), section("Rest", 1)}
+
+	tests := []struct {
+		name string
+		i    int
+		want int
+	}{
+		{"Work", 0, 0},
+		{"Work", 2, 2},
+		{"Work", 3, 2},
+		{"Rest", 0, 1},
+		{"Rest", 10, 3},
+		{"Plank", 1, -1},
+	}
+
+	for _, tt := range tests {
+		if got := nearest(sections, tt.name, tt.i); got != tt.want {
+			t.Errorf("nearest(%s, %d) = %d, want %d", tt.name, tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestRescheduled(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+	wait := document.Section{Name: "Waiting for Dinner", Kind: document.KindPrep, Duration: 20 * time.Minute, Wait: true}
+	dinner := document.Section{Name: "Dinner", At: &document.Clock{Hour: 18, Minute: 30}, Duration: time.Hour}
+	bake := func(hour, minute int) document.Section {
+		return document.Section{Name: "Bake", Until: &document.Clock{Hour: hour, Minute: minute}}
+	}
+
+	tests := []struct {
+		name     string
+		sections []document.Section
+		plan     []document.Section
+		i        int
+		now      time.Time
+		spent    time.Duration
+		want     []string
+		found    bool
+	}{
+		{"played anchors are kept",
+			[]document.Section{section("Work", 10), wait, dinner, section("Tea", 5)},
+			[]document.Section{section("Work", 15), dinner, section("Tea", 10)},
+			2, at(18, 40), 10 * time.Minute,
+			[]string{"Work 10m0s", "Waiting for Dinner 20m0s", "Dinner 1h0m0s", "Tea 10m0s"}, true},
+		{"until from the start of the section",
+			[]document.Section{section("Work", 10), {Name: "Bake", Duration: 50 * time.Minute}},
+			[]document.Section{section("Work", 20), bake(19, 30), section("Cool", 5)},
+			1, at(18, 20), 10 * time.Minute,
+			[]string{"Work 10m0s", "Bake 1h20m0s", "Cool 5m0s"}, true},
+		{"anchors ahead are scheduled",
+			[]document.Section{section("Work", 10), section("Tea", 5)},
+			[]document.Section{section("Work", 10), section("Tea", 5), dinner},
+			1, at(18, 12), 2 * time.Minute,
+			[]string{"Work 10m0s", "Tea 5m0s", "Waiting for Dinner 15m0s", "Dinner 1h0m0s"}, true},
+		{"repeated names",
+			[]document.Section{section("Work", 1), section("Rest", 1), section("Work", 1), section("Rest", 1)},
+			[]document.Section{section("Work", 2), section("Rest", 2), section("Work", 2), section("Rest", 2), section("Work", 2)},
+			2, at(18, 0), 0,
+			[]string{"Work 1m0s", "Rest 1m0s", "Work 2m0s", "Rest 2m0s", "Work 2m0s"}, true},
+		{"removed section",
+			[]document.Section{section("A", 1), section("B", 1), section("C", 1)},
+			[]document.Section{section("A", 1), section("X", 2), section("C", 2)},
+			1, at(18, 0), 0,
+			[]string{"A 1m0s", "X 2m0s", "C 2m0s"}, false},
+		{"shorter round",
+			[]document.Section{section("A", 1), section("B", 1), section("C", 1)},
+			[]document.Section{section("A", 1)},
+			2, at(18, 0), 0,
+			[]string{"A 1m0s", "B 1m0s"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := document.Document{Loop: true, Location: time.UTC}
+			sections, found := rescheduled(doc, tt.sections, tt.plan, tt.i, tt.now, tt.spent)
+			if got := summarize(sections); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sections = %q, want %q", got, tt.want)
+			}
+			if found != tt.found {
+				t.Errorf("found = %v, want %v", found, tt.found)
+			}
+		})
+	}
+}
+
+func TestResumed(t *testing.T) {
+	doc := document.Document{Sections: []document.Section{section("Work", 2), section("Rest", 1)}}
+	saved := []document.Section{section("Work", 3), section("Rest", 1), section("Work", 3)}
+	planned := doc.Sections
+
+	tests := []struct {
+		name      string
+		hash      string
+		index     int
+		sections  []document.Section
+		want      []string
+		wantIndex int
+		elapsed   time.Duration
+	}{
+		{"same document", history.Hash(doc), 2, saved,
+			[]string{"Work 3m0s", "Rest 1m0s", "Work 3m0s"}, 2, 2 * time.Minute},
+		{"changed document", "changed", 0, saved,
+			[]string{"Work 2m0s", "Rest 1m0s"}, 0, time.Minute},
+		{"renamed section", "changed", 1, []document.Section{section("Work", 3), section("Stretch", 1)},
+			[]string{"Work 2m0s", "Rest 1m0s"}, 1, 0},
+		{"shorter round", "changed", 2, saved,
+			[]string{"Work 2m0s", "Rest 1m0s"}, 1, 0},
+		{"no saved sections", history.Hash(doc), 1, nil,
+			[]string{"Work 2m0s", "Rest 1m0s"}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := Bipper{doc: doc, resume: &history.Checkpoint{
+				Hash:      tt.hash,
+				Index:     tt.index,
+				Remaining: time.Minute,
+				Sections:  tt.sections,
+			}}
+
+			sections, index, elapsed := o.resumed(planned)
+			if got := summarize(sections); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sections = %q, want %q", got, tt.want)
+			}
+			if index != tt.wantIndex || elapsed != tt.elapsed {
+				t.Errorf("index %d elapsed %v, want %d %v", index, elapsed, tt.wantIndex, tt.elapsed)
+			}
+		})
+	}
+
+	// A round left with nothing to play starts from its beginning
+	o := Bipper{doc: doc, resume: &history.Checkpoint{Hash: "changed", Index: 3}}
+	if sections, index, elapsed := o.resumed(nil); len(sections) != 0 || index != 0 || elapsed != 0 {
+		t.Errorf("resumed(nil) = %v %d %v, want nothing", sections, index, elapsed)
+	}
+}
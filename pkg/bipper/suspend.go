@@ -0,0 +1,92 @@
+package bipper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+// SuspendPolicy is what the bipper does when it wakes up
+// after the computer was suspended during a section
+type SuspendPolicy string
+
+const (
+	// SuspendPause pauses the section where it was suspended
+	SuspendPause SuspendPolicy = "pause"
+	// SuspendCatchUp counts the suspended time in the section, and
+	// skips the sections that would have ended in the meantime
+	SuspendCatchUp SuspendPolicy = "catch-up"
+	// SuspendAlarm catches up and rings the missed alarm
+	// cue if a section ended in the meantime
+	SuspendAlarm SuspendPolicy = "alarm"
+)
+
+// SuspendPolicies lists every suspend policy
+var SuspendPolicies = []SuspendPolicy{SuspendPause, SuspendCatchUp, SuspendAlarm}
+
+// ParseSuspendPolicy returns the suspend policy named name
+func ParseSuspendPolicy(name string) (SuspendPolicy, error) {
+	var names []string
+	for _, p := range SuspendPolicies {
+		if string(p) == name {
+			return p, nil
+		}
+		names = append(names, string(p))
+	}
+
+	return "", fmt.Errorf("unknown suspend policy %q (available policies are %s)", name, strings.Join(names, ", "))
+}
+
+// suspendThreshold is the shortest gap between two ticks of the wall
+// clock read as a suspension. The monotonic clock the ticks are based
+// on stops while the computer is suspended, the wall clock does not.
+const suspendThreshold = 5 * time.Second
+
+// suspension returns the time the wall clock jumped between the ticks
+// read at last and now, zero if it is too short to be a suspension.
+// The times must have no monotonic clock reading.
+func suspension(last, now time.Time) time.Duration {
+	missed := (now.Sub(last) - time.Second).Round(time.Second)
+	if missed < suspendThreshold {
+		return 0
+	}
+	return missed
+}
+
+// skipMissed catches up the time missed while suspended from the first
+// of sections, elapsed is the time already spent in it. It returns the
+// number of sections that would have ended in the meantime, the time
+// spent in the next one and the missed time left if every section ended.
+func skipMissed(sections []document.Section, elapsed, missed time.Duration) (skipped int, spent, left time.Duration) {
+	for _, s := range sections {
+		if rest := s.Duration - elapsed; missed >= rest && rest > 0 {
+			missed -= rest
+			elapsed = 0
+			skipped++
+			continue
+		}
+		return skipped, elapsed + missed, 0
+	}
+	return skipped, elapsed, missed
+}
+
+// missedAlarmCue is the number of times the end sound is played
+// when the end of a section was missed while suspended
+const missedAlarmCue = 3
+
+// missedAlarmInterval is the time between two sounds of the missed alarm cue
+const missedAlarmInterval = 700 * time.Millisecond
+
+// ringMissedAlarm plays the missed alarm cue, it returns at once.
+// The player serialises the cue and the sounds played by Bip.
+func (o *Bipper) ringMissedAlarm() {
+	player := o.endPlayer
+	go func() {
+		for i := 0; i < missedAlarmCue; i++ {
+			player.Play()
+			time.Sleep(missedAlarmInterval)
+		}
+	}()
+}
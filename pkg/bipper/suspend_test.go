@@ -0,0 +1,100 @@
+package bipper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+func TestSuspension(t *testing.T) {
+	last := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"next tick", last.Add(time.Second), 0},
+		{"late tick", last.Add(1500 * time.Millisecond), 0},
+		{"below the threshold", last.Add(suspendThreshold), 0},
+		{"threshold", last.Add(suspendThreshold + time.Second), suspendThreshold},
+		{"suspended", last.Add(10*time.Minute + time.Second), 10 * time.Minute},
+		{"rounded", last.Add(time.Hour + 1400*time.Millisecond), time.Hour},
+		{"clock set back", last.Add(-time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		if got := suspension(last, tt.now); got != tt.want {
+			t.Errorf("%s: suspension = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipMissed(t *testing.T) {
+	round := []document.Section{section("A", 1), section("B", 2), section("C", 1)}
+
+	tests := []struct {
+		name     string
+		sections []document.Section
+		elapsed  time.Duration
+		missed   time.Duration
+		skipped  int
+		spent    time.Duration
+		left     time.Duration
+	}{
+		{"within the section", round, 10 * time.Second, 30 * time.Second, 0, 40 * time.Second, 0},
+		{"end of the section", round, 0, time.Minute, 1, 0, 0},
+		{"across sections", round, 30 * time.Second, 90 * time.Second, 1, time.Minute, 0},
+		{"several sections", round, 0, 3*time.Minute + 20*time.Second, 2, 20 * time.Second, 0},
+		{"across rounds", round, 0, 5 * time.Minute, 3, 0, time.Minute},
+		{"from the middle of the round", round[1:], time.Minute, 90 * time.Second, 1, 30 * time.Second, 0},
+		{"section already over", []document.Section{section("A", 1)}, 2 * time.Minute, 10 * time.Second, 0, 2*time.Minute + 10*time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		skipped, spent, left := skipMissed(tt.sections, tt.elapsed, tt.missed)
+		if skipped != tt.skipped || spent != tt.spent || left != tt.left {
+			t.Errorf("%s: skipMissed = %d %v %v, want %d %v %v", tt.name, skipped, spent, left, tt.skipped, tt.spent, tt.left)
+		}
+	}
+}
+
+// countingPlayer counts the sounds played
+type countingPlayer struct {
+	played chan time.Time
+}
+
+func (o *countingPlayer) Read(file string) {}
+func (o *countingPlayer) Play()            { o.played <- time.Now() }
+func (o *countingPlayer) Close()           {}
+
+func TestRingMissedAlarm(t *testing.T) {
+	player := &countingPlayer{played: make(chan time.Time)}
+	o := Bipper{endPlayer: player}
+
+	start := time.Now()
+	o.ringMissedAlarm()
+	if time.Since(start) >= missedAlarmInterval {
+		t.Error("ringMissedAlarm waits for the cue to be played")
+	}
+
+	var last time.Time
+	for i := 0; i < missedAlarmCue; i++ {
+		select {
+		case at := <-player.played:
+			if i > 0 && at.Sub(last) < missedAlarmInterval {
+				t.Errorf("sound %d played %v after the previous one", i+1, at.Sub(last))
+			}
+			last = at
+		case <-time.After(2 * missedAlarmInterval):
+			t.Fatalf("%d sounds played, want %d", i, missedAlarmCue)
+		}
+	}
+
+	select {
+	case <-player.played:
+		t.Errorf("more than %d sounds played", missedAlarmCue)
+	case <-time.After(2 * missedAlarmInterval):
+	}
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+// command is a bipper sub-command such as "run" or "convert"
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	runCommand,
+	convertCommand,
+	presetCommand,
+	resumeCommand,
+	historyCommand,
+	statsCommand,
+	exportCommand,
+	configCommand,
+}
+
+// Run executes the sub-command named by the first argument.
+// The "run" command is used when no sub-command is given.
+func Run(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return silenceHelp(runCommand.run(args))
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return silenceHelp(c.run(args[1:]))
+		}
+	}
+
+	if args[0] == "help" {
+		usage()
+		return nil
+	}
+
+	usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: bipper <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.usage, c.help)
+	}
+	w.Flush()
+}
+
+// silenceHelp drops the error returned when -h is requested,
+// the flag package has already printed the usage
+func silenceHelp(err error) error {
+	if err == flag.ErrHelp {
+		return nil
+	}
+	return err
+}
+
+// newFlagSet creates the flag set of the sub-command c
+func newFlagSet(c string) *flag.FlagSet {
+	return flag.NewFlagSet("bipper "+c, flag.ContinueOnError)
+}
+
+// varsFlag collects the name=value pairs of repeated -set flags
+type varsFlag map[string]string
+
+func (o varsFlag) String() string {
+	var pairs []string
+	for k, v := range o {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (o varsFlag) Set(pair string) error {
+	i := strings.Index(pair, "=")
+	if i <= 0 {
+		return fmt.Errorf("expected name=value, got %q", pair)
+	}
+	o[pair[:i]] = pair[i+1:]
+	return nil
+}
+
+// documentFlags registers the flags customising how documents are read
+func documentFlags(fs *flag.FlagSet) *document.Options {
+	opts := &document.Options{Vars: map[string]string{}}
+	fs.Var(varsFlag(opts.Vars), "set", "Override a document variable, name=value (repeatable).")
+	fs.DurationVar(&opts.Prestart, "prestart", 0, "Duration of the \"get ready\" countdown (default = the document's).")
+	return opts
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Juli3nnicolas/bipper/pkg/bipper"
+	"github.com/Juli3nnicolas/bipper/pkg/sound"
+	"github.com/Juli3nnicolas/bipper/pkg/ui"
+	"gopkg.in/yaml.v2"
+)
+
+var configCommand = command{
+	name:  "config",
+	usage: "config show [run flags]",
+	help:  "print the configuration merged from the config file, the environment and the flags",
+	run:   runConfig,
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "show" {
+		return fmt.Errorf("config expects the show sub-command")
+	}
+
+	fs := newFlagSet("config show")
+	flags := registerUIFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := flags.config()
+	if err != nil {
+		return err
+	}
+
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	file := configFile()
+	if _, err := os.Stat(file); err != nil {
+		file += " (missing)"
+	}
+	fmt.Printf("# config file: %s\n---\n%s", file, content)
+	return nil
+}
+
+// config is the user configuration. Its values are read from the config
+// file, then from the environment variables and finally from the flags.
+type config struct {
+	Terminal string `yaml:"terminal"`
+	Plans    string `yaml:"plans"`
+	Theme    string `yaml:"theme"`
+	// Colors is the number of colors of the terminal, 8 or 256
+	Colors int    `yaml:"colors"`
+	Volume int    `yaml:"volume"`
+	Sounds sounds `yaml:"sounds"`
+	// Suspend is the policy applied when the computer was
+	// suspended during a section, one of bipper.SuspendPolicies
+	Suspend string                 `yaml:"suspend"`
+	Keys    map[string]ui.KeyNames `yaml:"keys"`
+}
+
+// sounds are the sound files played at the end of the sections
+type sounds struct {
+	// Bip is played during the last seconds of a section
+	Bip string `yaml:"bip"`
+	// End is played when a section is over
+	End string `yaml:"end"`
+}
+
+func defaultConfig() config {
+	return config{
+		Terminal: "termbox",
+		Plans:    ".",
+		Theme:    "default",
+		Colors:   256,
+		Volume:   sound.MaxVolume,
+		Sounds:   sounds{Bip: "bip.mp3", End: "end_bip.mp3"},
+		Suspend:  string(bipper.SuspendPause),
+		Keys:     ui.DefaultBindings().Names(),
+	}
+}
+
+// configFile returns the path of the config file, $BIPPER_CONFIG
+// or bipper/config.yaml in the user's config directory
+func configFile() string {
+	if file := os.Getenv("BIPPER_CONFIG"); file != "" {
+		return file
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join("bipper", "config.yaml")
+	}
+	return filepath.Join(dir, "bipper", "config.yaml")
+}
+
+// readConfig returns the default configuration overridden by
+// the config file, if any, and by the environment variables
+func readConfig() (config, error) {
+	cfg := defaultConfig()
+
+	file := configFile()
+	content, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+	if err == nil {
+		// Keys that are not listed keep their default binding
+		keys := cfg.Keys
+		cfg.Keys = nil
+		if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+			return cfg, fmt.Errorf("%s: %v", file, err)
+		}
+		for action, names := range cfg.Keys {
+			keys[action] = names
+		}
+		cfg.Keys = keys
+	}
+
+	if err := cfg.readEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// readEnv overrides the configuration with the BIPPER_* environment variables
+func (o *config) readEnv() error {
+	for name, value := range map[string]*string{
+		"BIPPER_TERMINAL": &o.Terminal,
+		"BIPPER_PLANS":    &o.Plans,
+		"BIPPER_THEME":    &o.Theme,
+		"BIPPER_BIP":      &o.Sounds.Bip,
+		"BIPPER_END_BIP":  &o.Sounds.End,
+		"BIPPER_SUSPEND":  &o.Suspend,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*value = v
+		}
+	}
+
+	for name, value := range map[string]*int{
+		"BIPPER_VOLUME": &o.Volume,
+		"BIPPER_COLORS": &o.Colors,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s must be a number, got %q", name, v)
+			}
+			*value = n
+		}
+	}
+
+	return nil
+}
+
+// readKeys overrides the key bindings with the ones of file
+func (o *config) readKeys(file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var keys map[string]ui.KeyNames
+	if err := yaml.UnmarshalStrict(content, &keys); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for action, names := range keys {
+		o.Keys[action] = names
+	}
+	return nil
+}
+
+func (o config) validate() error {
+	if o.Terminal != "termbox" && o.Terminal != "tcell" {
+		return fmt.Errorf("unknown terminal %q (available terminals are termbox and tcell)", o.Terminal)
+	}
+	if o.Volume < 1 || o.Volume > sound.MaxVolume {
+		return fmt.Errorf("the volume must be between 1 and %d, got %d", sound.MaxVolume, o.Volume)
+	}
+	if o.Colors != 8 && o.Colors != 256 {
+		return fmt.Errorf("the terminal colors must be 8 or 256, got %d", o.Colors)
+	}
+	if _, err := bipper.ParseSuspendPolicy(o.Suspend); err != nil {
+		return err
+	}
+
+	for _, t := range ui.Themes {
+		if t == o.Theme {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown theme %q (available themes are %s)", o.Theme, strings.Join(ui.Themes, ", "))
+}
+
+// setFlags returns the names of the flags set on the command line
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
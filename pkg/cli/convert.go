@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+var convertCommand = command{
+	name:  "convert",
+	usage: "convert [-to format] [-o out] [-set name=value] [-prestart d] doc",
+	help:  "convert a document to yaml, json or toml (- for stdin)",
+	run:   convert,
+}
+
+func convert(args []string) error {
+	fs := newFlagSet("convert")
+	to := fs.String("to", "", "Output format: yaml, json or toml (default = guessed from -o, yaml otherwise).")
+	out := fs.String("o", "", "Output file (default = standard output).")
+	opts := documentFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("convert expects exactly one document")
+	}
+
+	_, doc, err := document.ReadWithOptions(fs.Arg(0), *opts)
+	if err != nil {
+		return err
+	}
+
+	format := document.YAML
+	if *to != "" {
+		if format, err = document.ParseFormat(*to); err != nil {
+			return err
+		}
+	} else if *out != "" {
+		format = document.DetectFormat(*out, nil)
+	}
+
+	content, err := document.Marshal(doc, format)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	return ioutil.WriteFile(*out, content, 0644)
+}
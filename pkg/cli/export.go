@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+)
+
+var exportCommand = command{
+	name:  "export",
+	usage: "export [-to format] [-o out] [-n count]",
+	help:  "export the past sessions to csv, ics (calendar) or tcx (fitness trackers)",
+	run:   export,
+}
+
+func export(args []string) error {
+	fs := newFlagSet("export")
+	to := fs.String("to", "", "Output format: csv, ics or tcx (default = guessed from -o, csv otherwise).")
+	out := fs.String("o", "", "Output file (default = standard output).")
+	count := fs.Int("n", 0, "Number of sessions exported, the latest ones (0 = all).")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("export expects no argument")
+	}
+
+	format := history.CSV
+	if *to != "" {
+		var err error
+		if format, err = history.ParseExportFormat(*to); err != nil {
+			return err
+		}
+	} else if *out != "" {
+		format = history.DetectExportFormat(*out)
+	}
+
+	sessions, err := history.Read()
+	if err != nil {
+		return err
+	}
+	if *count > 0 && len(sessions) > *count {
+		sessions = sessions[len(sessions)-*count:]
+	}
+
+	if *out == "" {
+		return history.Export(os.Stdout, sessions, format)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := history.Export(f, sessions, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+)
+
+var historyCommand = command{
+	name:  "history",
+	usage: "history [-n count] [-sections]",
+	help:  "list the past sessions, oldest first",
+	run:   runHistory,
+}
+
+func runHistory(args []string) error {
+	fs := newFlagSet("history")
+	count := fs.Int("n", 20, "Number of sessions listed, the latest ones (0 = all).")
+	sections := fs.Bool("sections", false, "List the sections played in every session.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("history expects no argument")
+	}
+
+	sessions, err := history.Read()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		file, err := history.File()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "No session recorded in %s\n", file)
+		return nil
+	}
+	if *count > 0 && len(sessions) > *count {
+		sessions = sessions[len(sessions)-*count:]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tLENGTH\tSTATUS\tSECTIONS\tPAUSES\tSKIPS\tDOCUMENT")
+	for _, s := range sessions {
+		status := "aborted"
+		if s.Completed {
+			status = "completed"
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%d\t%d\t%d\t%s\n",
+			s.Start.Local().Format("2006-01-02 15:04"),
+			s.End.Sub(s.Start).Round(time.Second),
+			status,
+			len(s.Sections),
+			s.Pauses(),
+			s.Skips(),
+			s.Document)
+
+		if *sections {
+			for _, section := range s.Sections {
+				skipped := ""
+				if section.Skipped {
+					skipped = "skipped"
+				}
+				fmt.Fprintf(w, "  %s\t%v/%v\t%s\t\t%d\t\t\n",
+					section.Name,
+					section.Actual.Round(time.Second),
+					section.Planned,
+					skipped,
+					section.Pauses)
+			}
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+var presetCommand = command{
+	name:  "preset",
+	usage: "preset [-emit format] [-list] [run flags] spec...",
+	help:  "play a preset workout such as \"tabata rounds=8\", or print it with -emit",
+	run:   runPreset,
+}
+
+func runPreset(args []string) error {
+	fs := newFlagSet("preset")
+	emit := fs.String("emit", "", "Print the expanded document in this format (yaml, json or toml) instead of playing it.")
+	list := fs.Bool("list", false, "List the available presets.")
+	flags := registerUIFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		for _, usage := range document.Presets() {
+			fmt.Println(usage)
+		}
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("preset expects a preset specification, use -list to list them")
+	}
+
+	spec := strings.Join(fs.Args(), " ")
+	doc, err := document.ExpandPreset(spec)
+	if err != nil {
+		return err
+	}
+	if flags.doc.Prestart != 0 {
+		doc.Prestart = flags.doc.Prestart
+	}
+
+	format := document.YAML
+	if *emit != "" {
+		if format, err = document.ParseFormat(*emit); err != nil {
+			return err
+		}
+	}
+
+	content, err := document.Marshal(doc, format)
+	if err != nil {
+		return err
+	}
+
+	if *emit != "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	return flags.run("preset "+spec, string(content), &doc)
+}
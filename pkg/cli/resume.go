@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+)
+
+var resumeCommand = command{
+	name:  "resume",
+	usage: "resume [-force] [run flags]",
+	help:  "continue the last interrupted session from its checkpoint",
+	run:   resume,
+}
+
+func resume(args []string) error {
+	fs := newFlagSet("resume")
+	force := fs.Bool("force", false, "Resume even if the document changed since the checkpoint.")
+	flags := registerUIFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("resume expects no argument")
+	}
+
+	c, err := history.ReadCheckpoint()
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("no session to resume")
+	}
+
+	// The variables of the checkpoint are overridden by the -set flags
+	opts := *flags.doc
+	opts.Vars = map[string]string{}
+	for name, value := range c.Vars {
+		opts.Vars[name] = value
+	}
+	for name, value := range flags.doc.Vars {
+		opts.Vars[name] = value
+	}
+
+	raw, doc, err := readCheckpointDocument(*c, opts)
+	if err != nil {
+		return err
+	}
+	if history.Hash(doc) != c.Hash {
+		position := fmt.Sprintf("section %d of round %d", c.Index+1, c.Round)
+		if s, ok := c.Section(); ok {
+			position = fmt.Sprintf("%q (%s)", s.Name, position)
+		}
+		if !*force {
+			return fmt.Errorf("%s changed since the checkpoint of %s, use -force to play the new version from %s",
+				c.Document, c.Saved.Local().Format("2006-01-02 15:04"), position)
+		}
+		fmt.Fprintf(os.Stderr, "%s changed since the checkpoint, playing the new version from %s\n", c.Document, position)
+	}
+
+	return flags.resume(c.Document, raw, &doc, *c)
+}
+
+// readCheckpointDocument reads the document of c from its file. The copy
+// saved in the checkpoint is read if the document is not a file, such as
+// the standard input or a preset, or if the file does not exist anymore.
+// The documents it includes are then still read next to its file.
+func readCheckpointDocument(c history.Checkpoint, opts document.Options) (raw string, doc document.Document, err error) {
+	if !filepath.IsAbs(c.Document) {
+		return document.ParseWithOptions(strings.NewReader(c.Raw), opts)
+	}
+
+	raw, doc, err = document.ReadWithOptions(c.Document, opts)
+	if !os.IsNotExist(err) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s does not exist anymore, resuming the copy saved %v ago\n",
+		c.Document, time.Since(c.Saved).Round(time.Second))
+
+	return document.ParseAs(c.Document, strings.NewReader(c.Raw), opts)
+}
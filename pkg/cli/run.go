@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Juli3nnicolas/bipper/pkg/bipper"
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+	"github.com/Juli3nnicolas/bipper/pkg/sound"
+	"github.com/Juli3nnicolas/bipper/pkg/ui"
+)
+
+var runCommand = command{
+	name:  "run",
+	usage: "run [-terminal termbox|tcell] [-plans dir] [-theme name] [-colors 8|256] [-volume n] [-bip file] [-end-bip file] [-keys file] [-suspend policy] [-set name=value] [-prestart d] [-seed n] [doc]",
+	help:  "open the terminal UI, doc is played at once (- for stdin)",
+	run:   run,
+}
+
+func run(args []string) error {
+	fs := newFlagSet("run")
+	flags := registerUIFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		return fmt.Errorf("run expects at most one document")
+	}
+
+	// The document is read before the UI starts so that
+	// the standard input can be used
+	if fs.NArg() == 1 {
+		raw, doc, err := document.ReadWithOptions(fs.Arg(0), *flags.doc)
+		if err != nil {
+			return err
+		}
+		return flags.run(documentPath(fs.Arg(0)), raw, &doc)
+	}
+
+	return flags.run("", "", nil)
+}
+
+// uiFlags are the flags of the commands opening the terminal UI.
+// They take precedence over the user configuration.
+type uiFlags struct {
+	fs       *flag.FlagSet
+	terminal *string
+	plans    *string
+	theme    *string
+	colors   *int
+	volume   *int
+	bip      *string
+	endBip   *string
+	keys     *string
+	suspend  *string
+	seed     *int64
+	doc      *document.Options
+}
+
+func registerUIFlags(fs *flag.FlagSet) uiFlags {
+	return uiFlags{
+		fs: fs,
+		terminal: fs.String("terminal",
+			"",
+			"The terminal implementation to use. Available implementations are 'termbox' and 'tcell' (default = the config's, termbox)."),
+		plans:   fs.String("plans", "", "The directory listed by the file browser (default = the config's, the working directory)."),
+		theme:   fs.String("theme", "", "The color theme, one of "+strings.Join(ui.Themes, ", ")+" (default = the config's, default)."),
+		colors:  fs.Int("colors", 0, "The number of colors of the terminal, 8 or 256 (default = the config's, 256)."),
+		volume:  fs.Int("volume", 0, fmt.Sprintf("The volume, from 1 to %d (default = the config's, %d).", sound.MaxVolume, sound.MaxVolume)),
+		bip:     fs.String("bip", "", "The sound played during the last seconds of a section (default = the config's, bip.mp3)."),
+		endBip:  fs.String("end-bip", "", "The sound played when a section is over (default = the config's, end_bip.mp3)."),
+		keys:    fs.String("keys", "", "A file mapping actions to keys, it overrides the keys of the config."),
+		suspend: fs.String("suspend", "", "What to do when the computer was suspended during a section: pause, catch-up or alarm (default = the config's, pause)."),
+		doc:     documentFlags(fs),
+		seed:    fs.Int64("seed", 0, "Seed of the random durations and section orders (default = random)."),
+	}
+}
+
+// config returns the user configuration overridden by the flags
+func (o uiFlags) config() (config, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return cfg, err
+	}
+
+	set := setFlags(o.fs)
+	for name, value := range map[string]*string{
+		"terminal": &cfg.Terminal,
+		"plans":    &cfg.Plans,
+		"theme":    &cfg.Theme,
+		"bip":      &cfg.Sounds.Bip,
+		"end-bip":  &cfg.Sounds.End,
+		"suspend":  &cfg.Suspend,
+	} {
+		if set[name] {
+			*value = o.fs.Lookup(name).Value.String()
+		}
+	}
+	if set["volume"] {
+		cfg.Volume = *o.volume
+	}
+	if set["colors"] {
+		cfg.Colors = *o.colors
+	}
+	if *o.keys != "" {
+		if err := cfg.readKeys(*o.keys); err != nil {
+			return cfg, err
+		}
+	}
+
+	return cfg, cfg.validate()
+}
+
+// documentPath returns the path of a document file as recorded in the history
+func documentPath(file string) string {
+	if file == "-" {
+		return "stdin"
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
+
+// run opens the terminal UI, doc is played at once if not nil.
+// path is where the document comes from.
+func (o uiFlags) run(path, raw string, doc *document.Document) error {
+	return o.start(path, raw, doc, nil)
+}
+
+// resume opens the terminal UI playing doc from checkpoint c
+func (o uiFlags) resume(path, raw string, doc *document.Document, c history.Checkpoint) error {
+	return o.start(path, raw, doc, &c)
+}
+
+func (o uiFlags) start(path, raw string, doc *document.Document, c *history.Checkpoint) error {
+	cfg, err := o.config()
+	if err != nil {
+		return err
+	}
+
+	bindings, err := ui.ParseBindings(cfg.Keys)
+	if err != nil {
+		return fmt.Errorf("keys: %v", err)
+	}
+	sound.SetVolume(cfg.Volume)
+	suspend, err := bipper.ParseSuspendPolicy(cfg.Suspend)
+	if err != nil {
+		return err
+	}
+
+	tui := ui.TermDashUI{}
+	tui.Init(cfg.Sounds.Bip, cfg.Sounds.End, cfg.Terminal)
+	tui.SetBindings(bindings)
+	tui.SetTheme(cfg.Theme)
+	tui.SetColors(cfg.Colors)
+	tui.SetDocumentOptions(*o.doc)
+	tui.SetPlansDir(cfg.Plans)
+	if setFlags(o.fs)["seed"] {
+		tui.SetSeed(*o.seed)
+	}
+	tui.SetSuspendPolicy(suspend)
+	if doc != nil {
+		tui.Open(path, raw, *doc)
+		if c != nil {
+			tui.Resume(*c)
+		}
+	}
+
+	tui.Run()
+
+	return nil
+}
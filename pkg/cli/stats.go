@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+)
+
+var statsCommand = command{
+	name:  "stats",
+	usage: "stats [-days n] [-periods n]",
+	help:  "report the time trained, the sessions per plan and the daily streaks",
+	run:   runStats,
+}
+
+func runStats(args []string) error {
+	fs := newFlagSet("stats")
+	days := fs.Int("days", 14, "Number of days of the chart, today included.")
+	periods := fs.Int("periods", 8, "Number of weeks and months listed, the latest ones.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("stats expects no argument")
+	}
+	if *days < 1 || *periods < 1 {
+		return fmt.Errorf("-days and -periods must be at least 1")
+	}
+
+	sessions, err := history.Read()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		file, err := history.File()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "No session recorded in %s\n", file)
+		return nil
+	}
+
+	fmt.Print(history.Compute(sessions, time.Now(), *days).Report(*periods))
+	return nil
+}
@@ -0,0 +1,95 @@
+package document
+
+import (
+	"fmt"
+	"time"
+)
+
+// clockLayouts are the accepted time of day formats
+var clockLayouts = []string{"15:04", "15:04:05", "3:04pm", "3:04PM"}
+
+// Clock is a time of the day anchoring a section to the wall clock
+type Clock struct {
+	Hour   int
+	Minute int
+	Second int
+}
+
+// ParseClock reads a time of the day such as "18:30" or "6:30pm"
+func ParseClock(s string) (*Clock, error) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &Clock{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second()}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid time of day %q (expected hh:mm or hh:mm:ss)", s)
+}
+
+func (o Clock) String() string {
+	if o.Second != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", o.Hour, o.Minute, o.Second)
+	}
+	return fmt.Sprintf("%02d:%02d", o.Hour, o.Minute)
+}
+
+// On returns the time of the clock on the day of t, in t's location
+func (o Clock) On(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, o.Hour, o.Minute, o.Second, 0, t.Location())
+}
+
+// Schedule resolves the clock anchored sections of a plan starting
+// at start. A waiting section is inserted before every section having
+// an At anchor, and the duration of the sections having an Until anchor
+// is computed. Anchors that are already passed are reported in warnings.
+// A looping document waits for them on the next day, sections whose
+// Until anchor is passed are dropped otherwise.
+func (o Document) Schedule(plan []Section, start time.Time) (scheduled []Section, warnings []string) {
+	loc := o.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	now := start.In(loc)
+
+	for _, s := range plan {
+		if s.At != nil {
+			at := s.At.On(now)
+			if at.Before(now) && o.Loop {
+				at = at.AddDate(0, 0, 1)
+				warnings = append(warnings, fmt.Sprintf("%s was due at %v, waiting until tomorrow", s.Name, s.At))
+			}
+			if at.Before(now) {
+				warnings = append(warnings, fmt.Sprintf("%s was due at %v", s.Name, s.At))
+			} else if wait := at.Sub(now).Round(time.Second); wait > 0 {
+				scheduled = append(scheduled, Section{
+					Name:     "Waiting for " + s.Name,
+					Kind:     KindPrep,
+					Duration: wait,
+					Wait:     true,
+				})
+				now = now.Add(wait)
+			}
+		}
+
+		if s.Until != nil {
+			until := s.Until.On(now)
+			if !until.After(now) && o.Loop {
+				until = until.AddDate(0, 0, 1)
+				warnings = append(warnings, fmt.Sprintf("%s should have ended at %v, it lasts until tomorrow", s.Name, s.Until))
+			}
+			// A section ending within a second would not be played
+			d := until.Sub(now).Round(time.Second)
+			if d <= 0 {
+				warnings = append(warnings, fmt.Sprintf("%s should have ended at %v", s.Name, s.Until))
+				continue
+			}
+			s.Duration = d
+		}
+
+		scheduled = append(scheduled, s)
+		now = now.Add(s.Duration)
+	}
+
+	return
+}
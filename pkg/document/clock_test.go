@@ -0,0 +1,84 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	work := Section{Name: "Work", Duration: 10 * time.Minute}
+	dinner := Section{Name: "Dinner", At: &Clock{Hour: 18, Minute: 30}, Duration: time.Hour}
+	bake := Section{Name: "Bake", Until: &Clock{Hour: 18, Minute: 45}}
+	lunch := Section{Name: "Lunch", At: &Clock{Hour: 12}, Duration: time.Hour}
+	nap := Section{Name: "Nap", Until: &Clock{Hour: 14}}
+
+	tests := []struct {
+		name      string
+		loop      bool
+		plan      []Section
+		durations map[string]time.Duration
+		warnings  []string
+	}{
+		{"at waits", false, []Section{work, dinner},
+			map[string]time.Duration{"Work": 10 * time.Minute, "Waiting for Dinner": 20 * time.Minute, "Dinner": time.Hour}, nil},
+		{"until lasts", false, []Section{work, bake},
+			map[string]time.Duration{"Work": 10 * time.Minute, "Bake": 35 * time.Minute}, nil},
+		{"passed at", false, []Section{lunch},
+			map[string]time.Duration{"Lunch": time.Hour}, []string{"Lunch was due at 12:00"}},
+		{"passed until", false, []Section{nap, work},
+			map[string]time.Duration{"Work": 10 * time.Minute}, []string{"Nap should have ended at 14:00"}},
+		{"looping passed at", true, []Section{lunch},
+			map[string]time.Duration{"Waiting for Lunch": 18 * time.Hour, "Lunch": time.Hour},
+			[]string{"Lunch was due at 12:00, waiting until tomorrow"}},
+		{"looping passed until", true, []Section{nap},
+			map[string]time.Duration{"Nap": 20 * time.Hour},
+			[]string{"Nap should have ended at 14:00, it lasts until tomorrow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Document{Loop: tt.loop, Location: time.UTC}
+			scheduled, warnings := doc.Schedule(tt.plan, start)
+
+			durations := map[string]time.Duration{}
+			for _, s := range scheduled {
+				durations[s.Name] = s.Duration
+			}
+			if !reflect.DeepEqual(durations, tt.durations) {
+				t.Errorf("durations = %v, want %v", durations, tt.durations)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestAnchorErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`sections: [{name: Dinner, at: "19:00"}]`, `section 1: "Dinner" needs a duration or an until time`},
+		{"sections: [{name: Plank}]", `section 1: "Plank" needs a duration or an until time`},
+		{`sections: [{name: Dinner, at: "7 pm", duration: 1h}]`, `section 1: at: invalid time of day "7 pm"`},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Parse(strings.NewReader(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q): err = %v, want %q", tt.content, err, tt.err)
+		}
+	}
+
+	_, doc, err := Parse(strings.NewReader(`sections: [{name: Dinner, at: "19:00", until: "20:00"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := doc.Sections[0]; s.At == nil || s.Until == nil || s.Duration != 0 {
+		t.Errorf("section = %+v, want at and until anchors", s)
+	}
+}
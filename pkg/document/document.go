@@ -1,63 +1,213 @@
 package document
 
 import (
-	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
 	"time"
-
-	"gopkg.in/yaml.v2"
 )
 
 type Document struct {
-	Loop     bool
+	Loop bool
+	// Shuffle is true if the sections are played
+	// in a random order at each loop
+	Shuffle bool
+	// Location is the time zone of the clock anchored
+	// sections, the local time zone is used if nil
+	Location *time.Location
+	// Prestart is the duration of the "get ready" countdown
+	// played before the first section, it is not part of the totals
+	Prestart time.Duration
 	Sections []Section
 	Dynamic
 }
 
 type Section struct {
-	Name     string
+	Name string
+	// Description is an optional text displayed under the name
+	Description string
+	Tags        []string
+	// Color overrides the color of the section's kind, it is either
+	// a color name, a 256 color palette number or a #rrggbb value
+	Color    string
+	Kind     Kind
 	Duration time.Duration
+	// MaxDuration is set when the duration is random, the section
+	// then lasts between Duration and MaxDuration
+	MaxDuration time.Duration
+	// Sections is set when the section is a group of sections
+	Sections []Section
+	// Shuffle is true if the group's sections are
+	// played in a random order at each loop
+	Shuffle bool
+	// At is set when the section must start at a time of the day
+	At *Clock
+	// Until is set when the section must end at a time of the day,
+	// its duration is then computed when the session is scheduled
+	Until *Clock
+	// Wait is true for the sections inserted by Schedule
+	// to wait for a section having an At anchor
+	Wait bool
+}
+
+// Kind is the type of activity of a section
+type Kind string
+
+const (
+	KindWork Kind = "work"
+	KindRest Kind = "rest"
+	KindPrep Kind = "prep"
+)
+
+// ParseKind returns the kind named name, the empty kind is accepted
+func ParseKind(name string) (Kind, error) {
+	switch k := Kind(name); k {
+	case "", KindWork, KindRest, KindPrep:
+		return k, nil
+	}
+
+	return "", fmt.Errorf("unknown kind %q (available kinds are work, rest and prep)", name)
+}
+
+// ColorNames lists the color names accepted by Section.Color
+var ColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// validColor returns an error if c is not a color name,
+// a 256 color palette number or a #rrggbb value
+func validColor(c string) error {
+	for _, name := range ColorNames {
+		if c == name {
+			return nil
+		}
+	}
+	if n, err := strconv.Atoi(c); err == nil && n >= 0 && n <= 255 {
+		return nil
+	}
+	if len(c) == 7 && c[0] == '#' {
+		if _, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid color %q (expected a color name, a number between 0 and 255 or #rrggbb)", c)
+}
+
+// IsGroup returns true if the section is a group of sections
+func (o Section) IsGroup() bool {
+	return o.Sections != nil
 }
 
 // Dynamic is a struct containing values computed
-// after the yaml doc has been read and the document
+// after the doc has been read and the document
 // struct hydrated
 type Dynamic struct {
-	// Total is the total time of every sections
+	// Total is the total time of every sections. It is
+	// the shortest possible total if the document is random.
 	Total time.Duration
+	// MaxTotal is the longest possible total, it equals
+	// Total if no duration is random
+	MaxTotal time.Duration
+	// Random is true if the order or the durations
+	// of the sections change at each loop
+	Random bool
+	// Scheduled is true if some sections are anchored to the wall
+	// clock. Waiting times and the durations of the sections having
+	// an Until anchor are not part of the totals.
+	Scheduled bool
+}
+
+// Stdin is the file name standing for the standard input
+const Stdin = "-"
+
+// Options customise how documents are read
+type Options struct {
+	// Vars override the values of the document's vars
+	Vars map[string]string
+	// Prestart overrides the document's prestart countdown if not zero
+	Prestart time.Duration
 }
 
+// Read parses the document stored in file. The format (yaml, json
+// or toml) is detected from the file extension or its content.
+// The document is read from the standard input if file is Stdin.
 func Read(file string) (raw string, doc Document, err error) {
+	return ReadWithOptions(file, Options{})
+}
+
+// ReadWithOptions is like Read but customises the document with opts
+func ReadWithOptions(file string, opts Options) (raw string, doc Document, err error) {
+	if file == Stdin {
+		return ParseWithOptions(os.Stdin, opts)
+	}
+
 	f, err := os.Open(file)
 	if err != nil {
 		return
 	}
+	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	return parse(file, f, opts)
+}
 
-	for scanner.Scan() {
-		raw += scanner.Text() + "\n"
-	}
+// Parse reads a document from r. The format (yaml, json or toml)
+// is detected from the content.
+func Parse(r io.Reader) (raw string, doc Document, err error) {
+	return ParseWithOptions(r, Options{})
+}
 
-	if err = scanner.Err(); err != nil {
-		return
-	}
+// ParseWithOptions is like Parse but customises the document with opts
+func ParseWithOptions(r io.Reader, opts Options) (raw string, doc Document, err error) {
+	return parse("", r, opts)
+}
 
-	err = yaml.Unmarshal([]byte(raw), &doc)
+// ParseAs is like ParseWithOptions but reads r as if it was the content
+// of file, which does not have to exist. The format is detected from
+// file and the included documents are resolved relatively to it.
+func ParseAs(file string, r io.Reader, opts Options) (raw string, doc Document, err error) {
+	return parse(file, r, opts)
+}
+
+// parse reads a document from r, file is only used to detect
+// the document's format and to resolve included documents
+func parse(file string, r io.Reader, opts Options) (raw string, doc Document, err error) {
+	content, err := ioutil.ReadAll(r)
 	if err != nil {
 		return
 	}
 
-	setDynamics(&doc)
-
+	raw = string(content)
+	doc, err = unmarshal(file, content, DetectFormat(file, content), opts)
 	return
 }
 
 // setDynamics sets all dynamics fields of Document doc
-// Dynamic attributes are generated after the yaml document
+// Dynamic attributes are generated after the document
 // has been successfuly parsed
 func setDynamics(doc *Document) {
-	for _, s := range doc.Sections {
-		doc.Total += s.Duration
+	doc.Dynamic = Dynamic{Random: doc.Shuffle}
+	addDynamics(&doc.Dynamic, doc.Sections)
+}
+
+func addDynamics(d *Dynamic, sections []Section) {
+	for _, s := range sections {
+		if s.IsGroup() {
+			d.Random = d.Random || s.Shuffle
+			addDynamics(d, s.Sections)
+			continue
+		}
+
+		if s.At != nil || s.Until != nil {
+			d.Scheduled = true
+		}
+
+		d.Total += s.Duration
+		if s.MaxDuration > s.Duration {
+			d.MaxTotal += s.MaxDuration
+			d.Random = true
+		} else {
+			d.MaxTotal += s.Duration
+		}
 	}
 }
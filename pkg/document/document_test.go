@@ -0,0 +1,352 @@
+package document
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// summarize lists the sections of doc as "name duration"
+func summarize(doc Document) []string {
+	var l []string
+	for _, s := range doc.Sections {
+		l = append(l, fmt.Sprintf("%s %v", s.Name, s.Duration))
+	}
+	return l
+}
+
+func TestParse(t *testing.T) {
+	want := []Section{
+		{Name: "Warmup", Duration: time.Minute},
+		{Name: "Work", Kind: KindWork, Duration: 40 * time.Second},
+		{Name: "Rest", Kind: KindRest, Duration: 20 * time.Second},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"yaml", `
+loop: true
+sections:
+  - name: Warmup
+    duration: 1m
+  - name: Work
+    kind: work
+    duration: 40s
+  - name: Rest
+    kind: rest
+    duration: 20
+`},
+		{"json", `{
+  "loop": true,
+  "sections": [
+    { "name": "Warmup", "duration": "1m" },
+    { "name": "Work", "kind": "work", "duration": "40s" },
+    { "name": "Rest", "kind": "rest", "duration": 20 }
+  ]
+}`},
+		{"toml", `
+loop = true
+
+[[sections]]
+name = "Warmup"
+duration = "1m"
+
+[[sections]]
+name = "Work"
+kind = "work"
+duration = "40s"
+
+[[sections]]
+name = "Rest"
+kind = "rest"
+duration = 20
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, doc, err := Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw != tt.content {
+				t.Errorf("raw = %q, want the content read", raw)
+			}
+			if !doc.Loop {
+				t.Error("loop = false, want true")
+			}
+			if !reflect.DeepEqual(doc.Sections, want) {
+				t.Errorf("sections = %+v, want %+v", doc.Sections, want)
+			}
+			if doc.Total != 2*time.Minute || doc.MaxTotal != 2*time.Minute {
+				t.Errorf("totals = %v %v, want 2m0s 2m0s", doc.Total, doc.MaxTotal)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"sections not a list", "sections: 3", "sections must be a list"},
+		{"section not a map", "sections: [3]", "section 1: must be a map"},
+		{"invalid duration", "sections: [{name: A, duration: forever}]", `section 1: invalid duration "forever"`},
+		{"invalid kind", "sections: [{name: A, kind: nap, duration: 1s}]", `section 1: unknown kind "nap"`},
+		{"invalid color", "sections: [{name: A, color: pink, duration: 1s}]", `section 1: invalid color "pink"`},
+		{"invalid timezone", "timezone: Mars/Olympus\nsections: [{name: A, duration: 1s}]", `invalid timezone "Mars/Olympus"`},
+		{"unknown template", "sections: [{use: missing}]", `unknown template "missing"`},
+		{"template cycle", "templates: {a: {use: a}}\nsections: [{use: a}]", "template a: template cycle: a -> a"},
+		{"empty group", "sections: [{name: A, duration: 1s}, {name: G, sections: []}]", `section 2: "G" is a group without sections`},
+		{"nested empty group", "sections: [{name: G, sections: [{name: H, sections: []}]}]", `section 1: section 1: "H" is a group without sections`},
+		{"group with at", `sections: [{name: G, at: "18:00", sections: [{name: A, duration: 1s}]}]`, `section 1: "G" is a group, only its sections can have an at or until time`},
+		{"group with until", `sections: [{name: G, until: "18:00", sections: [{name: A, duration: 1s}]}]`, `"G" is a group`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    Format
+	}{
+		{"plan.yml", "{}", YAML},
+		{"plan.json", "loop: true", JSON},
+		{"plan.toml", "", TOML},
+		{"", `{"loop": true}`, JSON},
+		{"", "# comment\nloop = true", TOML},
+		{"", "[[sections]]\nname = \"A\"", TOML},
+		{"", "loop: true", YAML},
+		{"", "name: \"a = b\"", YAML},
+		{"plan.txt", "---\nsections: []", YAML},
+	}
+
+	for _, tt := range tests {
+		if got := DetectFormat(tt.file, []byte(tt.content)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %v, want %v", tt.file, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	doc := Document{
+		Loop:     true,
+		Shuffle:  true,
+		Location: paris,
+		Prestart: 10 * time.Second,
+		Sections: []Section{
+			{Name: "Warmup", Description: "Easy pace", Tags: []string{"legs"}, Color: "#ff8800", Duration: time.Minute},
+			{Name: "Circuit", Shuffle: true, Sections: []Section{
+				{Name: "Squats", Kind: KindWork, Duration: 20 * time.Second, MaxDuration: 40 * time.Second},
+				{Name: "Rest", Kind: KindRest, Duration: 10 * time.Second},
+			}},
+			{Name: "Bake", At: &Clock{Hour: 18, Minute: 30}, Until: &Clock{Hour: 19}},
+		},
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			content, err := Marshal(doc, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Unmarshal(content, format)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, content)
+			}
+
+			if got.Location == nil || got.Location.String() != paris.String() {
+				t.Errorf("location = %v, want %v", got.Location, paris)
+			}
+			got.Location = paris
+			got.Dynamic = Dynamic{}
+			if !reflect.DeepEqual(got, doc) {
+				t.Errorf("got %+v, want %+v\n%s", got, doc, content)
+			}
+		})
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bipper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"warmup.yaml":  "sections: [{name: Jog, duration: 5m}, {include: stretch.json}]",
+		"stretch.json": `{"sections": [{"name": "Stretch", "duration": "2m"}]}`,
+		"a.yaml":       "sections: [{include: b.yaml}]",
+		"b.yaml":       "sections: [{name: B, duration: 1s}, {include: a.yaml}]",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, doc, err := Parse(strings.NewReader("sections:\n  - include: " + filepath.Join(dir, "warmup.yaml") + "\n  - name: Run\n    duration: 20m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range doc.Sections {
+		names = append(names, s.Name)
+	}
+	if want := []string{"Jog", "Stretch", "Run"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sections = %v, want %v", names, want)
+	}
+
+	// The included documents of a copy are resolved next to its file
+	_, doc, err = ParseAs(filepath.Join(dir, "deleted.yaml"), strings.NewReader("sections: [{include: stretch.json}]"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sections) != 1 || doc.Sections[0].Name != "Stretch" {
+		t.Errorf("sections = %+v, want Stretch", doc.Sections)
+	}
+
+	a := filepath.Join(dir, "a.yaml")
+	_, _, err = Parse(strings.NewReader("sections: [{include: " + a + "}]"))
+	want := "include cycle: " + a + " -> " + filepath.Join(dir, "b.yaml") + " -> " + a
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+
+	_, _, err = Parse(strings.NewReader("sections: [{include: " + filepath.Join(dir, "missing.yaml") + "}]"))
+	if !os.IsNotExist(err) {
+		t.Errorf("err = %v, want a missing file", err)
+	}
+}
+
+func TestIncludedVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bipper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"work.yaml": "vars: {w: 30s, r: '${w} / 2'}\nsections: [{name: 'Work ${w}', duration: '${w}'}, {name: G, sections: [{name: Rest, duration: '${r}'}, {include: cool.yaml}]}]",
+		"cool.yaml": "vars: {c: '${w} * 2'}\nsections: [{name: Cool, duration: '${c}'}]",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		want    []string
+	}{
+		{"own vars", "sections: [{include: work.yaml}]", nil,
+			[]string{"Work 30s 30s", "Rest 15s", "Cool 1m0s"}},
+		{"including document", "vars: {w: 10s}\nsections: [{include: work.yaml}, {name: Run, duration: '${w}'}]", nil,
+			[]string{"Work 10s 10s", "Rest 5s", "Cool 20s", "Run 10s"}},
+		{"set", "vars: {w: 10s}\nsections: [{include: work.yaml}]", map[string]string{"w": "1m"},
+			[]string{"Work 1m0s 1m0s", "Rest 30s", "Cool 2m0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "plan.yaml")
+			if err := ioutil.WriteFile(file, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, doc, err := ReadWithOptions(file, Options{Vars: tt.vars})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := summarize(Document{Sections: doc.Plan(nil)})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sections = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, _, err = Parse(strings.NewReader("sections: [{include: " + filepath.Join(dir, "cool.yaml") + "}]"))
+	if want := `unknown variable "w"`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	content := `
+templates:
+  interval:
+    - name: Work ${round}
+      duration: ${work}
+    - name: Rest ${round}
+      duration: ${rest}
+sections:
+  - use: interval
+    round: 1
+    work: 40s
+    rest: 20
+  - use: interval
+    round: 2
+    work: 30s
+    rest: 30s
+`
+	_, doc, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Section{
+		{Name: "Work 1", Duration: 40 * time.Second},
+		{Name: "Rest 1", Duration: 20 * time.Second},
+		{Name: "Work 2", Duration: 30 * time.Second},
+		{Name: "Rest 2", Duration: 30 * time.Second},
+	}
+	if !reflect.DeepEqual(doc.Sections, want) {
+		t.Errorf("sections = %+v, want %+v", doc.Sections, want)
+	}
+}
+
+func TestExpands(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"sections: [{name: A, duration: 1s}]", false},
+		{"sections: [{name: G, sections: [{name: A, duration: 1s}]}]", false},
+		{"vars: {w: 1s}\nsections: [{name: A, duration: '${w}'}]", true},
+		{"preset: tabata", true},
+		{"sections: [{include: other.yaml}]", true},
+		{"sections: [{name: G, sections: [{use: t}]}]", true},
+	}
+
+	for _, tt := range tests {
+		if got := Expands("plan.yaml", []byte(tt.content)); got != tt.want {
+			t.Errorf("Expands(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package document
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// expander resolves the include, use and preset nodes of a document tree.
+//
+// An include node ("include: warmup.yaml") splices the sections of
+// another document, its path is relative to the including document.
+// The included sections keep the vars of their document, see includedVars.
+//
+// A use node ("use: interval, work: 40s, rest: 20s") splices the
+// sections of a template declared in the templates map, every other
+// key is a parameter substituted to ${key} in the template.
+//
+// A preset node ("preset: tabata rounds=8") splices the sections
+// generated by a preset, see Presets.
+type expander struct {
+	// files is the chain of documents being included,
+	// used to detect include cycles
+	files []string
+	// uses is the chain of templates being expanded,
+	// used to detect templates using themselves
+	uses []string
+}
+
+// expand resolves the include, use and preset nodes of tree. file is the path
+// of the document tree was read from, it is empty if the document
+// does not come from a file.
+func expand(file string, tree map[string]interface{}) (map[string]interface{}, error) {
+	e := expander{}
+	dir := "."
+	if file != "" && file != Stdin {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		e.files = append(e.files, abs)
+		dir = filepath.Dir(abs)
+	}
+
+	sections, err := e.expandDocument(dir, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	tree["sections"] = sections
+	delete(tree, "templates")
+	delete(tree, "preset")
+	return tree, nil
+}
+
+// expandDocument returns the expanded sections of the document tree
+// located in directory dir
+func (o *expander) expandDocument(dir string, tree map[string]interface{}) ([]interface{}, error) {
+	templates, ok := tree["templates"].(map[string]interface{})
+	if !ok && tree["templates"] != nil {
+		return nil, fmt.Errorf("templates must be a map")
+	}
+
+	nodes, ok := tree["sections"].([]interface{})
+	if !ok && tree["sections"] != nil {
+		return nil, fmt.Errorf("sections must be a list")
+	}
+
+	// A document level preset comes before the document's sections
+	if spec := tree["preset"]; spec != nil {
+		generated, err := o.preset(spec)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(generated, nodes...)
+	}
+
+	return o.expandSections(dir, templates, nodes)
+}
+
+func (o *expander) expandSections(dir string, templates map[string]interface{}, nodes []interface{}) ([]interface{}, error) {
+	var sections []interface{}
+
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			sections = append(sections, n)
+			continue
+		}
+
+		switch {
+		case node["include"] != nil:
+			included, err := o.include(dir, node["include"])
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, included...)
+
+		case node["use"] != nil:
+			used, err := o.use(dir, templates, node)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, used...)
+
+		case node["preset"] != nil:
+			generated, err := o.preset(node["preset"])
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, generated...)
+
+		case node["sections"] != nil:
+			// Groups may include documents and use templates too
+			nested, ok := node["sections"].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("sections must be a list")
+			}
+			expanded, err := o.expandSections(dir, templates, nested)
+			if err != nil {
+				return nil, err
+			}
+			group := make(map[string]interface{}, len(node))
+			for k, v := range node {
+				group[k] = v
+			}
+			group["sections"] = expanded
+			sections = append(sections, group)
+
+		default:
+			sections = append(sections, node)
+		}
+	}
+
+	return sections, nil
+}
+
+// include returns the expanded sections of the document
+// located at path, relatively to directory dir
+func (o *expander) include(dir string, path interface{}) ([]interface{}, error) {
+	file, ok := path.(string)
+	if !ok {
+		return nil, fmt.Errorf("include must be a file path")
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+
+	for i, f := range o.files {
+		if f == file {
+			chain := append(o.files[i:], file)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := decodeTree(content, DetectFormat(file, content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	o.files = append(o.files, file)
+	defer func() { o.files = o.files[:len(o.files)-1] }()
+
+	sections, err := o.expandDocument(filepath.Dir(file), tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	if tree["vars"] != nil {
+		vars, ok := tree["vars"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: vars must be a map", file)
+		}
+		withVars(sections, vars)
+	}
+
+	return sections, nil
+}
+
+// includedVars is the key of the section nodes holding the vars of the
+// documents the section was included from, the closest document first.
+// The vars of the including documents take precedence, see scope.include.
+const includedVars = "$vars"
+
+// withVars adds vars to the included vars of the section nodes
+func withVars(nodes []interface{}, vars map[string]interface{}) {
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		chain, _ := node[includedVars].([]map[string]interface{})
+		node[includedVars] = append([]map[string]interface{}{vars}, chain...)
+
+		if nested, ok := node["sections"].([]interface{}); ok {
+			withVars(nested, vars)
+		}
+	}
+}
+
+// preset returns the section nodes generated by a preset specification
+func (o *expander) preset(spec interface{}) ([]interface{}, error) {
+	s, ok := spec.(string)
+	if !ok {
+		return nil, fmt.Errorf("preset must be a string such as \"tabata rounds=8\"")
+	}
+
+	return expandPreset(s)
+}
+
+// use returns the expanded sections of the template referenced by node
+func (o *expander) use(dir string, templates map[string]interface{}, node map[string]interface{}) ([]interface{}, error) {
+	name, ok := node["use"].(string)
+	if !ok {
+		return nil, fmt.Errorf("use must be a template name")
+	}
+
+	template, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %q", name)
+	}
+
+	for i, u := range o.uses {
+		if u == name {
+			chain := append(o.uses[i:], name)
+			return nil, fmt.Errorf("template cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	// A template is either a list of sections or a single section
+	body, ok := template.([]interface{})
+	if !ok {
+		body = []interface{}{template}
+	}
+
+	params := make(map[string]interface{}, len(node)-1)
+	for k, v := range node {
+		if k != "use" {
+			params[k] = v
+		}
+	}
+
+	o.uses = append(o.uses, name)
+	defer func() { o.uses = o.uses[:len(o.uses)-1] }()
+
+	sections, err := o.expandSections(dir, templates, substitute(body, params).([]interface{}))
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %v", name, err)
+	}
+
+	return sections, nil
+}
+
+// placeholder matches ${name} references
+var placeholder = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// substitute returns a copy of node where every ${key} reference to
+// a key of params is replaced by its value. Unknown references are
+// left untouched.
+func substitute(node interface{}, params map[string]interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[k] = substitute(v, params)
+		}
+		return m
+
+	case []interface{}:
+		l := make([]interface{}, len(n))
+		for i, v := range n {
+			l[i] = substitute(v, params)
+		}
+		return l
+
+	case string:
+		// Keep the parameter's type when it is referenced alone
+		if m := placeholder.FindStringSubmatch(n); m != nil && m[0] == n {
+			if v, ok := params[m[1]]; ok {
+				return v
+			}
+		}
+
+		return placeholder.ReplaceAllStringFunc(n, func(ref string) string {
+			if v, ok := params[ref[2:len(ref)-1]]; ok {
+				return fmt.Sprint(v)
+			}
+			return ref
+		})
+	}
+
+	return node
+}
+
+// Expands reports whether the document stored in file uses vars,
+// templates, includes or presets. They are resolved when the document
+// is read, a document marshalled back loses them.
+func Expands(file string, content []byte) bool {
+	tree, err := decodeTree(content, DetectFormat(file, content))
+	if err != nil {
+		return false
+	}
+
+	for _, key := range []string{"vars", "templates", "preset"} {
+		if tree[key] != nil {
+			return true
+		}
+	}
+	return expandsSections(tree["sections"])
+}
+
+func expandsSections(list interface{}) bool {
+	nodes, _ := list.([]interface{})
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if node["include"] != nil || node["use"] != nil || node["preset"] != nil {
+			return true
+		}
+		if expandsSections(node["sections"]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Format is the language a document is written in
+type Format string
+
+const (
+	YAML Format = "yaml"
+	JSON Format = "json"
+	TOML Format = "toml"
+)
+
+// Formats lists every supported document format
+var Formats = []Format{YAML, JSON, TOML}
+
+// ParseFormat returns the format matching name (case insensitive).
+// "yml" is accepted as an alias of "yaml".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "yaml", "yml":
+		return YAML, nil
+	case "json":
+		return JSON, nil
+	case "toml":
+		return TOML, nil
+	}
+
+	return "", fmt.Errorf("unknown document format %q (available formats are yaml, json and toml)", name)
+}
+
+// DetectFormat guesses the format of a document. The file extension
+// is used first, the content is sniffed when the extension is missing
+// or unknown. YAML is the fallback.
+func DetectFormat(file string, content []byte) Format {
+	if ext := strings.TrimPrefix(filepath.Ext(file), "."); ext != "" {
+		if f, err := ParseFormat(ext); err == nil {
+			return f
+		}
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return JSON
+	}
+
+	// TOML documents start with a "key = value" pair or a table header
+	// whereas YAML documents use colons
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return TOML
+		}
+		eq := strings.Index(line, "=")
+		colon := strings.Index(line, ":")
+		if eq > 0 && (colon < 0 || eq < colon) {
+			return TOML
+		}
+		break
+	}
+
+	return YAML
+}
+
+// Unmarshal parses content written in the given format
+// and returns the hydrated document. Included documents
+// are resolved relatively to the working directory.
+func Unmarshal(content []byte, format Format) (doc Document, err error) {
+	return unmarshal("", content, format, Options{})
+}
+
+// unmarshal parses content read from file, file is used
+// to resolve the paths of included documents
+func unmarshal(file string, content []byte, format Format, opts Options) (doc Document, err error) {
+	tree, err := decodeTree(content, format)
+	if err != nil {
+		return
+	}
+
+	if tree, err = expand(file, tree); err != nil {
+		return
+	}
+
+	doc, err = hydrate(tree, opts.Vars)
+	if err != nil {
+		return
+	}
+	if opts.Prestart != 0 {
+		doc.Prestart = opts.Prestart
+	}
+
+	setDynamics(&doc)
+
+	return
+}
+
+// Marshal writes doc in the given format. Only the static
+// attributes are written, dynamics are always recomputed on read.
+func Marshal(doc Document, format Format) ([]byte, error) {
+	enc := encode(doc)
+
+	switch format {
+	case YAML:
+		out, err := yaml.Marshal(enc)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte("---\n"), out...), nil
+
+	case JSON:
+		out, err := json.MarshalIndent(enc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+
+	case TOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(enc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("unknown document format %q", format)
+}
+
+// decodeTree parses content into a generic tree made of maps, slices
+// and scalars, whatever the source format is
+func decodeTree(content []byte, format Format) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+
+	switch format {
+	case YAML:
+		var raw map[interface{}]interface{}
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, err
+		}
+		tree = normalize(raw).(map[string]interface{})
+
+	case JSON:
+		if err := json.Unmarshal(content, &tree); err != nil {
+			return nil, err
+		}
+
+	case TOML:
+		if _, err := toml.Decode(string(content), &tree); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown document format %q", format)
+	}
+
+	return normalize(tree).(map[string]interface{}), nil
+}
+
+// normalize converts the map[interface{}]interface{} nodes produced
+// by the yaml decoder into map[string]interface{} nodes so that
+// every format yields the same kind of tree
+func normalize(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+
+	case map[string]interface{}:
+		for k, v := range n {
+			n[k] = normalize(v)
+		}
+		return n
+
+	case []interface{}:
+		for i, v := range n {
+			n[i] = normalize(v)
+		}
+		return n
+
+	case []map[string]interface{}:
+		// TOML arrays of tables
+		l := make([]interface{}, len(n))
+		for i, v := range n {
+			l[i] = normalize(v)
+		}
+		return l
+	}
+
+	return node
+}
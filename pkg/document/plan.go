@@ -0,0 +1,51 @@
+package document
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Plan returns the sections played during one loop of the document.
+// Groups are flattened, shuffled sections are reordered and random
+// durations are picked using r. Every returned section has a fixed
+// duration.
+func (o Document) Plan(r *rand.Rand) []Section {
+	return plan(r, o.Sections, o.Shuffle)
+}
+
+func plan(r *rand.Rand, sections []Section, shuffle bool) []Section {
+	order := make([]Section, len(sections))
+	copy(order, sections)
+	if shuffle {
+		r.Shuffle(len(order), func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+
+	var planned []Section
+	for _, s := range order {
+		if s.IsGroup() {
+			planned = append(planned, plan(r, s.Sections, s.Shuffle)...)
+			continue
+		}
+
+		if s.MaxDuration > s.Duration {
+			s.Duration = pickDuration(r, s.Duration, s.MaxDuration)
+			s.MaxDuration = 0
+		}
+		planned = append(planned, s)
+	}
+
+	return planned
+}
+
+// pickDuration returns a random duration between min and max,
+// with a one second granularity
+func pickDuration(r *rand.Rand, min, max time.Duration) time.Duration {
+	steps := int64((max - min) / time.Second)
+	if steps <= 0 {
+		return min
+	}
+
+	return min + time.Duration(r.Int63n(steps+1))*time.Second
+}
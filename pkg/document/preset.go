@@ -0,0 +1,370 @@
+package document
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// preset generates the section nodes of a well known workout
+type preset struct {
+	usage string
+	// params lists the parameter names with their default value,
+	// an empty default makes the parameter mandatory
+	params map[string]string
+	// positional lists the parameters that can be given without a name
+	positional []string
+	// variadic is true if extra positional arguments are accepted
+	variadic bool
+	expand   func(p presetParams) ([]interface{}, error)
+}
+
+var presets = map[string]preset{
+	"tabata": {
+		usage:      "tabata [rounds=8] [work=20s] [rest=10s]",
+		params:     map[string]string{"rounds": "8", "work": "20s", "rest": "10s"},
+		positional: []string{"rounds"},
+		expand: func(p presetParams) ([]interface{}, error) {
+			if err := checkDuration("work", p.values["work"], false); err != nil {
+				return nil, err
+			}
+			if err := checkDuration("rest", p.values["rest"], true); err != nil {
+				return nil, err
+			}
+			return intervals(p, []string{p.values["work"]}, []string{p.values["rest"]})
+		},
+	},
+	"emom": {
+		usage:      "emom [minutes=10] [every=1m]",
+		params:     map[string]string{"minutes": "10", "every": "1m"},
+		positional: []string{"minutes"},
+		expand: func(p presetParams) ([]interface{}, error) {
+			n, err := p.count("minutes")
+			if err != nil {
+				return nil, err
+			}
+			if err := checkDuration("every", p.values["every"], false); err != nil {
+				return nil, err
+			}
+
+			if err := checkCount(int64(n)); err != nil {
+				return nil, err
+			}
+
+			var nodes []interface{}
+			for i := 1; i <= n; i++ {
+				nodes = append(nodes, sectionNode(fmt.Sprintf("Minute %d/%d", i, n), p.values["every"], KindWork))
+			}
+			return nodes, nil
+		},
+	},
+	"amrap": {
+		usage:      "amrap duration",
+		params:     map[string]string{"duration": ""},
+		positional: []string{"duration"},
+		expand: func(p presetParams) ([]interface{}, error) {
+			if err := checkDuration("duration", p.values["duration"], false); err != nil {
+				return nil, err
+			}
+			return []interface{}{sectionNode("AMRAP", p.values["duration"], KindWork)}, nil
+		},
+	},
+	"pyramid": {
+		usage:      "pyramid min..max [step=15s] [rest=0s]",
+		params:     map[string]string{"range": "", "step": "15s", "rest": "0s"},
+		positional: []string{"range"},
+		expand:     pyramid,
+	},
+	"intervals": {
+		usage:    "intervals work/rest... [rounds=1]",
+		params:   map[string]string{"rounds": "1"},
+		variadic: true,
+		expand: func(p presetParams) ([]interface{}, error) {
+			if len(p.rest) == 0 {
+				return nil, fmt.Errorf("at least one work/rest interval is expected")
+			}
+
+			var work, rest []string
+			for _, pair := range p.rest {
+				wr := strings.SplitN(pair, "/", 2)
+				work = append(work, wr[0])
+				if len(wr) == 2 {
+					rest = append(rest, wr[1])
+				} else {
+					rest = append(rest, "0s")
+				}
+				if err := checkDuration("work", work[len(work)-1], false); err != nil {
+					return nil, err
+				}
+				if err := checkDuration("rest", rest[len(rest)-1], true); err != nil {
+					return nil, err
+				}
+			}
+			return intervals(p, work, rest)
+		},
+	},
+}
+
+// presetParams are the parameters of a preset specification
+type presetParams struct {
+	values map[string]string
+	// rest holds the positional arguments left once
+	// the preset's positional parameters are set
+	rest []string
+}
+
+func (o presetParams) count(name string) (int, error) {
+	n, err := strconv.Atoi(o.values[name])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, o.values[name])
+	}
+	return n, nil
+}
+
+func (o presetParams) duration(name string) (time.Duration, error) {
+	d, err := time.ParseDuration(o.values[name])
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration, got %q", name, o.values[name])
+	}
+	return d, nil
+}
+
+// maxPresetSections is the largest number of sections a preset generates
+const maxPresetSections = 1000
+
+// checkCount returns an error if n sections are too many to be generated
+func checkCount(n int64) error {
+	if n > maxPresetSections {
+		return fmt.Errorf("more than %d sections would be generated", maxPresetSections)
+	}
+	return nil
+}
+
+// checkDuration returns an error if value is a negative duration, or
+// zero if zero is false. Numbers are read as seconds, other values such
+// as variable references are checked when the sections are read.
+func checkDuration(name, value string, zero bool) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil
+		}
+		d = time.Duration(n * float64(time.Second))
+	}
+
+	switch {
+	case d < 0:
+		return fmt.Errorf("%s cannot be negative, got %q", name, value)
+	case d == 0 && !zero:
+		return fmt.Errorf("%s must be longer than 0s, got %q", name, value)
+	}
+	return nil
+}
+
+// Presets returns the usage of every preset, sorted by name
+func Presets() []string {
+	var usages []string
+	for _, p := range presets {
+		usages = append(usages, p.usage)
+	}
+	sort.Strings(usages)
+
+	return usages
+}
+
+// ExpandPreset returns the document made of the sections of the
+// preset described by spec, such as "tabata rounds=8 work=20s".
+func ExpandPreset(spec string) (doc Document, err error) {
+	nodes, err := expandPreset(spec)
+	if err != nil {
+		return
+	}
+
+	doc, err = hydrate(map[string]interface{}{"sections": nodes}, nil)
+	if err != nil {
+		return
+	}
+
+	setDynamics(&doc)
+	return
+}
+
+// expandPreset returns the section nodes of the preset described by spec.
+// Parameters are given as name=value, "name value" or positionally.
+func expandPreset(spec string) ([]interface{}, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty preset")
+	}
+
+	p, ok := presets[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (available presets are %s)", fields[0], strings.Join(presetNames(), ", "))
+	}
+
+	params := presetParams{values: map[string]string{}}
+	for k, v := range p.params {
+		params.values[k] = v
+	}
+
+	positional := p.positional
+	args := fields[1:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if eq := strings.Index(arg, "="); eq > 0 {
+			if _, ok := p.params[arg[:eq]]; !ok {
+				return nil, fmt.Errorf("%s: unknown parameter %q", fields[0], arg[:eq])
+			}
+			params.values[arg[:eq]] = arg[eq+1:]
+			continue
+		}
+
+		if _, ok := p.params[arg]; ok && i+1 < len(args) {
+			params.values[arg] = args[i+1]
+			i++
+			continue
+		}
+
+		if len(positional) > 0 {
+			params.values[positional[0]] = arg
+			positional = positional[1:]
+			continue
+		}
+
+		params.rest = append(params.rest, arg)
+	}
+
+	for k, v := range params.values {
+		if v == "" {
+			return nil, fmt.Errorf("%s: missing parameter %s (usage: %s)", fields[0], k, p.usage)
+		}
+	}
+	if len(params.rest) > 0 && !p.variadic {
+		return nil, fmt.Errorf("%s: unexpected arguments %s (usage: %s)", fields[0], strings.Join(params.rest, " "), p.usage)
+	}
+
+	nodes, err := p.expand(params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fields[0], err)
+	}
+
+	return nodes, nil
+}
+
+func presetNames() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func sectionNode(name, duration string, kind Kind) map[string]interface{} {
+	return map[string]interface{}{"name": name, "duration": duration, "kind": string(kind)}
+}
+
+// intervals repeats the work and rest durations for the
+// number of rounds given by the "rounds" parameter
+func intervals(p presetParams, work, rest []string) ([]interface{}, error) {
+	rounds, err := p.count("rounds")
+	if err != nil {
+		return nil, err
+	}
+
+	perRound := 0
+	for i := range work {
+		perRound++
+		if rest[i] != "0" && rest[i] != "0s" {
+			perRound++
+		}
+	}
+	// The number of rounds is checked first as the count could overflow
+	if err := checkCount(int64(rounds)); err != nil {
+		return nil, err
+	}
+	if err := checkCount(int64(rounds) * int64(perRound)); err != nil {
+		return nil, err
+	}
+
+	var nodes []interface{}
+	for r := 1; r <= rounds; r++ {
+		for i := range work {
+			nodes = append(nodes, sectionNode(fmt.Sprintf("Work %d/%d", r, rounds), work[i], KindWork))
+			if rest[i] != "0" && rest[i] != "0s" {
+				nodes = append(nodes, sectionNode(fmt.Sprintf("Rest %d/%d", r, rounds), rest[i], KindRest))
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// pyramid climbs from the lower to the upper bound of the "range"
+// parameter by "step" and climbs down back to the lower bound
+func pyramid(p presetParams) ([]interface{}, error) {
+	bounds := strings.SplitN(p.values["range"], rangeSeparator, 2)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("range must be written min..max, got %q", p.values["range"])
+	}
+	min, err := time.ParseDuration(bounds[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q", bounds[0])
+	}
+	max, err := time.ParseDuration(bounds[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q", bounds[1])
+	}
+	step, err := p.duration("step")
+	if err != nil {
+		return nil, err
+	}
+	rest, err := p.duration("rest")
+	if err != nil {
+		return nil, err
+	}
+	if min <= 0 {
+		return nil, fmt.Errorf("the lower bound of the range must be longer than 0s, got %q", bounds[0])
+	}
+	if step <= 0 || max < min {
+		return nil, fmt.Errorf("the step must be positive and the range increasing")
+	}
+	if rest < 0 {
+		return nil, fmt.Errorf("rest cannot be negative, got %q", p.values["rest"])
+	}
+
+	// The pyramid climbs n steps up and n-1 down, with a rest between each
+	n := int64((max-min)/step) + 1
+	if err := checkCount(n); err != nil {
+		return nil, err
+	}
+	count := 2*n - 1
+	if rest > 0 {
+		count += count - 1
+	}
+	if err := checkCount(count); err != nil {
+		return nil, err
+	}
+
+	var steps []time.Duration
+	for d := min; d <= max; d += step {
+		steps = append(steps, d)
+	}
+	for i := len(steps) - 2; i >= 0; i-- {
+		steps = append(steps, steps[i])
+	}
+
+	var nodes []interface{}
+	for i, d := range steps {
+		nodes = append(nodes, sectionNode(fmt.Sprintf("Work %v", d), d.String(), KindWork))
+		if rest > 0 && i < len(steps)-1 {
+			nodes = append(nodes, sectionNode("Rest", rest.String(), KindRest))
+		}
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,104 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandPreset(t *testing.T) {
+	tests := []struct {
+		spec  string
+		want  []string
+		total time.Duration
+	}{
+		{"tabata rounds=2", []string{"Work 1/2 20s", "Rest 1/2 10s", "Work 2/2 20s", "Rest 2/2 10s"}, time.Minute},
+		{"tabata 1 work=30s rest=0s", []string{"Work 1/1 30s"}, 30 * time.Second},
+		{"tabata rounds 1 work 45", []string{"Work 1/1 45s", "Rest 1/1 10s"}, 55 * time.Second},
+		{"emom 3", []string{"Minute 1/3 1m0s", "Minute 2/3 1m0s", "Minute 3/3 1m0s"}, 3 * time.Minute},
+		{"emom minutes=2 every=90s", []string{"Minute 1/2 1m30s", "Minute 2/2 1m30s"}, 3 * time.Minute},
+		{"amrap 15m", []string{"AMRAP 15m0s"}, 15 * time.Minute},
+		{"pyramid 30s..1m", []string{"Work 30s 30s", "Work 45s 45s", "Work 1m0s 1m0s", "Work 45s 45s", "Work 30s 30s"}, 210 * time.Second},
+		{"pyramid 10s..20s step=10s rest=5s", []string{"Work 10s 10s", "Rest 5s", "Work 20s 20s", "Rest 5s", "Work 10s 10s"}, 50 * time.Second},
+		{"intervals 40s/20s 30s", []string{"Work 1/1 40s", "Rest 1/1 20s", "Work 1/1 30s"}, 90 * time.Second},
+		{"intervals 10s/5s rounds=2", []string{"Work 1/2 10s", "Rest 1/2 5s", "Work 2/2 10s", "Rest 2/2 5s"}, 30 * time.Second},
+		{"tabata rounds=500 rest=0s", nil, 10000 * time.Second},
+		{"pyramid 1s..500s step=1s", nil, 250000 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			doc, err := ExpandPreset(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(doc); tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sections = %q, want %q", got, tt.want)
+			}
+			if doc.Total != tt.total {
+				t.Errorf("total = %v, want %v", doc.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestExpandPresetErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{"", "empty preset"},
+		{"yoga", `unknown preset "yoga" (available presets are amrap, emom, intervals, pyramid, tabata)`},
+		{"tabata sets=3", `tabata: unknown parameter "sets"`},
+		{"tabata 8 9", "tabata: unexpected arguments 9"},
+		{"tabata rounds=0", `tabata: rounds must be a positive integer, got "0"`},
+		{"tabata work=0s", `tabata: work must be longer than 0s, got "0s"`},
+		{"tabata rest=-10s", `tabata: rest cannot be negative, got "-10s"`},
+		{"emom every=0", `emom: every must be longer than 0s, got "0"`},
+		{"amrap", "amrap: missing parameter duration"},
+		{"amrap -1m", `amrap: duration cannot be negative, got "-1m"`},
+		{"pyramid 30s", `pyramid: range must be written min..max, got "30s"`},
+		{"pyramid 0s..1m", `pyramid: the lower bound of the range must be longer than 0s, got "0s"`},
+		{"pyramid 1m..30s", "pyramid: the step must be positive and the range increasing"},
+		{"pyramid 30s..1m step=0s", "pyramid: the step must be positive and the range increasing"},
+		{"pyramid 30s..1m rest=-5s", `pyramid: rest cannot be negative, got "-5s"`},
+		{"intervals", "intervals: at least one work/rest interval is expected"},
+		{"intervals 0s/10s", `intervals: work must be longer than 0s, got "0s"`},
+		{"intervals 10s/-5s", `intervals: rest cannot be negative, got "-5s"`},
+		{"emom 1001", "emom: more than 1000 sections would be generated"},
+		{"emom 9223372036854775807", "emom: more than 1000 sections would be generated"},
+		{"tabata rounds=501", "tabata: more than 1000 sections would be generated"},
+		{"intervals 10s/5s 10s 20s/5s rounds=201", "intervals: more than 1000 sections would be generated"},
+		{"pyramid 1s..100h step=1s", "pyramid: more than 1000 sections would be generated"},
+		{"pyramid 1ns..2562047h step=1ns", "pyramid: more than 1000 sections would be generated"},
+		{"pyramid 1s..251s step=1s rest=1s", "pyramid: more than 1000 sections would be generated"},
+	}
+
+	for _, tt := range tests {
+		_, err := ExpandPreset(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ExpandPreset(%q): err = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestPresetNode(t *testing.T) {
+	content := `
+vars:
+  work: 30s
+preset: tabata rounds=1 work=${work}
+sections:
+  - name: Cool down
+    preset: amrap 5m
+`
+	_, doc, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Work 1/1 30s", "Rest 1/1 10s", "AMRAP 5m0s"}
+	if got := summarize(doc); !reflect.DeepEqual(got, want) {
+		t.Errorf("sections = %q, want %q", got, want)
+	}
+}
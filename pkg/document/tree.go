@@ -0,0 +1,295 @@
+package document
+
+import (
+	"fmt"
+	"time"
+)
+
+// hydrate builds a Document out of a generic tree decoded from
+// any of the supported formats. vars override the document's vars.
+func hydrate(tree map[string]interface{}, vars map[string]string) (doc Document, err error) {
+	if doc.Loop, err = boolField(tree, "loop"); err != nil {
+		return
+	}
+	if doc.Shuffle, err = boolField(tree, "shuffle"); err != nil {
+		return
+	}
+
+	var tz string
+	if tz, err = stringField(tree, "timezone"); err != nil {
+		return
+	}
+	if tz != "" {
+		if doc.Location, err = time.LoadLocation(tz); err != nil {
+			err = fmt.Errorf("invalid timezone %q", tz)
+			return
+		}
+	}
+
+	s, err := newScope(tree, vars)
+	if err != nil {
+		return
+	}
+
+	if doc.Prestart, err = s.duration(tree["prestart"]); err != nil {
+		err = fmt.Errorf("prestart: %v", err)
+		return
+	}
+
+	doc.Sections, err = hydrateSections(s, tree["sections"])
+	return
+}
+
+func hydrateSections(vars *scope, list interface{}) (sections []Section, err error) {
+	nodes, ok := list.([]interface{})
+	if !ok && list != nil {
+		err = fmt.Errorf("sections must be a list")
+		return
+	}
+
+	for i, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("section %d: must be a map", i+1)
+			return
+		}
+
+		var section Section
+		if section, err = hydrateSection(vars, node); err != nil {
+			err = fmt.Errorf("section %d: %v", i+1, err)
+			return
+		}
+		sections = append(sections, section)
+	}
+
+	return
+}
+
+func hydrateSection(vars *scope, node map[string]interface{}) (s Section, err error) {
+	if chain, ok := node[includedVars].([]map[string]interface{}); ok {
+		vars = vars.include(chain)
+	}
+
+	if s.Name, err = stringField(node, "name"); err != nil {
+		return
+	}
+	s.Name = vars.interpolate(s.Name)
+
+	if s.Description, err = stringField(node, "description"); err != nil {
+		return
+	}
+	s.Description = vars.interpolate(s.Description)
+
+	if s.Tags, err = stringsField(node, "tags"); err != nil {
+		return
+	}
+
+	if s.Color, err = stringField(node, "color"); err != nil {
+		return
+	}
+	if s.Color != "" {
+		if err = validColor(s.Color); err != nil {
+			return
+		}
+	}
+
+	var kind string
+	if kind, err = stringField(node, "kind"); err != nil {
+		return
+	}
+	if s.Kind, err = ParseKind(kind); err != nil {
+		return
+	}
+
+	// Groups hold sections instead of a duration
+	if node["sections"] != nil {
+		if node["at"] != nil || node["until"] != nil {
+			err = fmt.Errorf("%q is a group, only its sections can have an at or until time", s.Name)
+			return
+		}
+		if s.Shuffle, err = boolField(node, "shuffle"); err != nil {
+			return
+		}
+		if s.Sections, err = hydrateSections(vars, node["sections"]); err != nil {
+			return
+		}
+		if len(s.Sections) == 0 {
+			err = fmt.Errorf("%q is a group without sections", s.Name)
+		}
+		return
+	}
+
+	if s.At, err = clockField(node, "at"); err != nil {
+		return
+	}
+	if s.Until, err = clockField(node, "until"); err != nil {
+		return
+	}
+
+	if s.Duration, s.MaxDuration, err = vars.durationRange(node["duration"]); err != nil {
+		return
+	}
+	// The duration of a section having an until anchor
+	// is computed when the session is scheduled
+	switch {
+	case node["duration"] == nil && s.Until == nil:
+		err = fmt.Errorf("%q needs a duration or an until time", s.Name)
+	case node["duration"] != nil && s.Duration <= 0:
+		// An expression may compute a duration that cannot be played
+		err = fmt.Errorf("%q must last longer than 0s, its duration is %v", s.Name, s.Duration)
+	}
+	return
+}
+
+// parseDuration reads a duration written with the golang time
+// format ("1m30s"). Plain numbers are read as seconds.
+func parseDuration(v interface{}) (time.Duration, error) {
+	switch d := v.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		parsed, err := time.ParseDuration(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", d)
+		}
+		return parsed, nil
+	case int:
+		return time.Duration(d) * time.Second, nil
+	case int64:
+		return time.Duration(d) * time.Second, nil
+	case float64:
+		return time.Duration(d * float64(time.Second)), nil
+	}
+
+	return 0, fmt.Errorf("invalid duration %v", v)
+}
+
+func stringField(node map[string]interface{}, key string) (string, error) {
+	switch v := node[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case int, int64, float64, bool:
+		return fmt.Sprint(v), nil
+	}
+
+	return "", fmt.Errorf("%s must be a string", key)
+}
+
+// stringsField reads a list of strings, a single string is
+// read as a list of one element
+func stringsField(node map[string]interface{}, key string) ([]string, error) {
+	switch v := node[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		l := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", key)
+			}
+			l[i] = s
+		}
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("%s must be a list of strings", key)
+}
+
+func clockField(node map[string]interface{}, key string) (*Clock, error) {
+	s, err := stringField(node, key)
+	if err != nil || s == "" {
+		return nil, err
+	}
+
+	c, err := ParseClock(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", key, err)
+	}
+	return c, nil
+}
+
+func boolField(node map[string]interface{}, key string) (bool, error) {
+	switch v := node[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+
+	return false, fmt.Errorf("%s must be a boolean", key)
+}
+
+// encodedDocument is the serialised form of a Document
+type encodedDocument struct {
+	Loop     bool             `yaml:"loop" json:"loop" toml:"loop"`
+	Shuffle  bool             `yaml:"shuffle,omitempty" json:"shuffle,omitempty" toml:"shuffle,omitempty"`
+	Timezone string           `yaml:"timezone,omitempty" json:"timezone,omitempty" toml:"timezone,omitempty"`
+	Prestart string           `yaml:"prestart,omitempty" json:"prestart,omitempty" toml:"prestart,omitempty"`
+	Sections []encodedSection `yaml:"sections" json:"sections" toml:"sections"`
+}
+
+type encodedSection struct {
+	Name        string           `yaml:"name,omitempty" json:"name,omitempty" toml:"name,omitempty"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty" toml:"description,omitempty"`
+	Tags        []string         `yaml:"tags,omitempty" json:"tags,omitempty" toml:"tags,omitempty"`
+	Color       string           `yaml:"color,omitempty" json:"color,omitempty" toml:"color,omitempty"`
+	Kind        string           `yaml:"kind,omitempty" json:"kind,omitempty" toml:"kind,omitempty"`
+	Duration    string           `yaml:"duration,omitempty" json:"duration,omitempty" toml:"duration,omitempty"`
+	At          string           `yaml:"at,omitempty" json:"at,omitempty" toml:"at,omitempty"`
+	Until       string           `yaml:"until,omitempty" json:"until,omitempty" toml:"until,omitempty"`
+	Shuffle     bool             `yaml:"shuffle,omitempty" json:"shuffle,omitempty" toml:"shuffle,omitempty"`
+	Sections    []encodedSection `yaml:"sections,omitempty" json:"sections,omitempty" toml:"sections,omitempty"`
+}
+
+func encode(doc Document) encodedDocument {
+	enc := encodedDocument{
+		Loop:     doc.Loop,
+		Shuffle:  doc.Shuffle,
+		Sections: encodeSections(doc.Sections),
+	}
+	if doc.Location != nil {
+		enc.Timezone = doc.Location.String()
+	}
+	if doc.Prestart != 0 {
+		enc.Prestart = doc.Prestart.String()
+	}
+
+	return enc
+}
+
+func encodeSections(sections []Section) []encodedSection {
+	var enc []encodedSection
+	for _, s := range sections {
+		e := encodedSection{
+			Name:        s.Name,
+			Description: s.Description,
+			Tags:        s.Tags,
+			Color:       s.Color,
+			Kind:        string(s.Kind),
+		}
+		if s.At != nil {
+			e.At = s.At.String()
+		}
+		if s.Until != nil {
+			e.Until = s.Until.String()
+		}
+
+		switch {
+		case s.IsGroup():
+			e.Shuffle = s.Shuffle
+			e.Sections = encodeSections(s.Sections)
+		case s.MaxDuration > s.Duration:
+			e.Duration = s.Duration.String() + rangeSeparator + s.MaxDuration.String()
+		case s.Duration != 0 || s.Until == nil:
+			e.Duration = s.Duration.String()
+		}
+		enc = append(enc, e)
+	}
+
+	return enc
+}
@@ -0,0 +1,386 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value is the result of an expression, either a duration or a number
+type value struct {
+	isDuration bool
+	d          time.Duration
+	n          float64
+}
+
+// duration converts v to a duration, numbers are read as seconds
+func (v value) duration() time.Duration {
+	if v.isDuration {
+		return v.d
+	}
+	return time.Duration(v.n * float64(time.Second))
+}
+
+func (v value) String() string {
+	if v.isDuration {
+		return v.d.String()
+	}
+	return strconv.FormatFloat(v.n, 'f', -1, 64)
+}
+
+// scope holds the variables of a document. Variables are evaluated
+// lazily so that they can reference each other in any order.
+type scope struct {
+	defs      map[string]interface{}
+	values    map[string]value
+	resolving map[string]bool
+}
+
+// newScope creates the scope of a document tree. overrides take
+// precedence over the vars declared in the tree.
+func newScope(tree map[string]interface{}, overrides map[string]string) (*scope, error) {
+	s := &scope{
+		defs:      map[string]interface{}{},
+		values:    map[string]value{},
+		resolving: map[string]bool{},
+	}
+
+	vars, ok := tree["vars"].(map[string]interface{})
+	if !ok && tree["vars"] != nil {
+		return nil, fmt.Errorf("vars must be a map")
+	}
+	for k, v := range vars {
+		s.defs[k] = v
+	}
+	for k, v := range overrides {
+		s.defs[k] = v
+	}
+
+	return s, nil
+}
+
+// include returns the scope of a section included from other
+// documents, chain holds the vars of these documents, the closest
+// one first. The variables of o take precedence over them.
+func (o *scope) include(chain []map[string]interface{}) *scope {
+	s := &scope{
+		defs:      map[string]interface{}{},
+		values:    map[string]value{},
+		resolving: map[string]bool{},
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k, v := range chain[i] {
+			s.defs[k] = v
+		}
+	}
+	for k, v := range o.defs {
+		s.defs[k] = v
+	}
+
+	return s
+}
+
+// lookup returns the value of variable name
+func (o *scope) lookup(name string) (value, error) {
+	if v, ok := o.values[name]; ok {
+		return v, nil
+	}
+
+	def, ok := o.defs[name]
+	if !ok {
+		return value{}, fmt.Errorf("unknown variable %q", name)
+	}
+	if o.resolving[name] {
+		return value{}, fmt.Errorf("variable %q references itself", name)
+	}
+
+	o.resolving[name] = true
+	defer delete(o.resolving, name)
+
+	var v value
+	var err error
+	switch d := def.(type) {
+	case string:
+		v, err = o.eval(d)
+	case int:
+		v = value{n: float64(d)}
+	case int64:
+		v = value{n: float64(d)}
+	case float64:
+		v = value{n: d}
+	default:
+		err = fmt.Errorf("invalid value %v", def)
+	}
+	if err != nil {
+		return value{}, fmt.Errorf("variable %s: %v", name, err)
+	}
+
+	o.values[name] = v
+	return v, nil
+}
+
+// duration evaluates a duration node. Strings are expressions,
+// plain numbers are read as seconds.
+func (o *scope) duration(node interface{}) (time.Duration, error) {
+	expr, ok := node.(string)
+	if !ok {
+		return parseDuration(node)
+	}
+
+	v, err := o.eval(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %v", expr, err)
+	}
+
+	return v.duration(), nil
+}
+
+// rangeSeparator separates the bounds of a random duration ("20s..40s")
+const rangeSeparator = ".."
+
+// durationRange evaluates a duration node that may be a random
+// range. max is zero if the duration is not random.
+func (o *scope) durationRange(node interface{}) (min, max time.Duration, err error) {
+	expr, ok := node.(string)
+	if !ok || !strings.Contains(expr, rangeSeparator) {
+		min, err = o.duration(node)
+		return
+	}
+
+	bounds := strings.SplitN(expr, rangeSeparator, 2)
+	if min, err = o.duration(strings.TrimSpace(bounds[0])); err != nil {
+		return
+	}
+	if max, err = o.duration(strings.TrimSpace(bounds[1])); err != nil {
+		return
+	}
+	switch {
+	case min <= 0:
+		err = fmt.Errorf("invalid duration %q: the lower bound must be longer than 0s", expr)
+	case max < min:
+		err = fmt.Errorf("invalid duration %q: the upper bound is lower than the lower bound", expr)
+	}
+
+	return
+}
+
+// interpolate replaces the ${name} references of text by the value
+// of the variables. Variables that are not expressions are written as is.
+func (o *scope) interpolate(text string) string {
+	return placeholder.ReplaceAllStringFunc(text, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+		if v, err := o.lookup(name); err == nil {
+			return v.String()
+		}
+		if def, ok := o.defs[name]; ok {
+			return fmt.Sprint(def)
+		}
+		return ref
+	})
+}
+
+// eval evaluates an arithmetic expression made of durations ("1m30s"),
+// numbers, ${name} variable references, parentheses and the + - * /
+// operators
+func (o *scope) eval(expr string) (value, error) {
+	p := parser{scope: o, input: expr}
+	v, err := p.expr()
+	if err != nil {
+		return value{}, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return value{}, fmt.Errorf("unexpected %q", p.input[p.pos:])
+	}
+
+	return v, nil
+}
+
+// parser is a recursive descent parser evaluating expressions:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = "-" factor | "(" expr ")" | "${" name "}" | literal
+type parser struct {
+	scope *scope
+	input string
+	pos   int
+}
+
+func (o *parser) skipSpaces() {
+	for o.pos < len(o.input) && o.input[o.pos] == ' ' {
+		o.pos++
+	}
+}
+
+// peek returns the next non-space character, 0 at the end of the input
+func (o *parser) peek() byte {
+	o.skipSpaces()
+	if o.pos < len(o.input) {
+		return o.input[o.pos]
+	}
+	return 0
+}
+
+func (o *parser) expr() (value, error) {
+	left, err := o.term()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		op := o.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		o.pos++
+
+		right, err := o.term()
+		if err != nil {
+			return value{}, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return value{}, err
+		}
+	}
+}
+
+func (o *parser) term() (value, error) {
+	left, err := o.factor()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		op := o.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		o.pos++
+
+		right, err := o.factor()
+		if err != nil {
+			return value{}, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return value{}, err
+		}
+	}
+}
+
+func (o *parser) factor() (value, error) {
+	switch c := o.peek(); {
+	case c == 0:
+		return value{}, fmt.Errorf("unexpected end of expression")
+
+	case c == '-':
+		o.pos++
+		v, err := o.factor()
+		v.d, v.n = -v.d, -v.n
+		return v, err
+
+	case c == '(':
+		o.pos++
+		v, err := o.expr()
+		if err != nil {
+			return value{}, err
+		}
+		if o.peek() != ')' {
+			return value{}, fmt.Errorf("missing closing parenthesis")
+		}
+		o.pos++
+		return v, nil
+
+	case strings.HasPrefix(o.input[o.pos:], "${"):
+		end := strings.IndexByte(o.input[o.pos:], '}')
+		if end < 0 {
+			return value{}, fmt.Errorf("unterminated variable reference")
+		}
+		name := o.input[o.pos+2 : o.pos+end]
+		o.pos += end + 1
+		return o.scope.lookup(name)
+	}
+
+	return o.literal()
+}
+
+// literal reads a number ("8", "0.5") or a duration ("1m30s")
+func (o *parser) literal() (value, error) {
+	start := o.pos
+	letters := false
+	for o.pos < len(o.input) {
+		c := o.input[o.pos]
+		if c >= 'a' && c <= 'z' || c >= 0x80 { // µs
+			letters = true
+		} else if !(c >= '0' && c <= '9' || c == '.') {
+			break
+		}
+		o.pos++
+	}
+
+	lit := o.input[start:o.pos]
+	if lit == "" {
+		return value{}, fmt.Errorf("unexpected %q", o.input[start:])
+	}
+
+	if letters {
+		d, err := time.ParseDuration(lit)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid duration %q", lit)
+		}
+		return value{isDuration: true, d: d}, nil
+	}
+
+	n, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		return value{}, fmt.Errorf("invalid number %q", lit)
+	}
+	return value{n: n}, nil
+}
+
+// apply computes "left op right". Durations can be added to and
+// subtracted from each other, multiplied and divided by numbers.
+// Dividing a duration by another one gives a number.
+func apply(op byte, left, right value) (value, error) {
+	switch op {
+	case '+', '-':
+		if left.isDuration != right.isDuration {
+			// Numbers are seconds when mixed with durations
+			left, right = value{isDuration: true, d: left.duration()}, value{isDuration: true, d: right.duration()}
+		}
+		if op == '-' {
+			right.d, right.n = -right.d, -right.n
+		}
+		return value{isDuration: left.isDuration, d: left.d + right.d, n: left.n + right.n}, nil
+
+	case '*':
+		switch {
+		case left.isDuration && right.isDuration:
+			return value{}, fmt.Errorf("cannot multiply two durations")
+		case left.isDuration:
+			return value{isDuration: true, d: time.Duration(float64(left.d) * right.n)}, nil
+		case right.isDuration:
+			return value{isDuration: true, d: time.Duration(left.n * float64(right.d))}, nil
+		}
+		return value{n: left.n * right.n}, nil
+
+	case '/':
+		if right.d == 0 && right.n == 0 {
+			return value{}, fmt.Errorf("division by zero")
+		}
+		switch {
+		case left.isDuration && right.isDuration:
+			return value{n: float64(left.d) / float64(right.d)}, nil
+		case left.isDuration:
+			return value{isDuration: true, d: time.Duration(float64(left.d) / right.n)}, nil
+		case right.isDuration:
+			return value{}, fmt.Errorf("cannot divide a number by a duration")
+		}
+		return value{n: left.n / right.n}, nil
+	}
+
+	return value{}, fmt.Errorf("unknown operator %q", op)
+}
@@ -0,0 +1,162 @@
+package document
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	s := &scope{
+		defs: map[string]interface{}{
+			"work":   "40s",
+			"rounds": 8,
+			"half":   0.5,
+			"total":  "${work} * ${rounds}",
+		},
+		values:    map[string]value{},
+		resolving: map[string]bool{},
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"1m30s", "1m30s"},
+		{"90", "90"},
+		{"1m + 30s", "1m30s"},
+		{"1m + 30", "1m30s"},
+		{"2 + 3 * 4", "14"},
+		{"(2 + 3) * 4", "20"},
+		{"10 - 4 - 3", "3"},
+		{"24 / 4 / 2", "3"},
+		{"-2 * 3", "-6"},
+		{"2 * -3", "-6"},
+		{"1m - 2 * 10s", "40s"},
+		{"(1m - 10s) / 2", "25s"},
+		{"1m / 20s", "3"},
+		{"${work} * 2", "1m20s"},
+		{"${work} * ${half}", "20s"},
+		{"${total} / 16", "20s"},
+		{"  ( ${work}+20s )  ", "1m0s"},
+	}
+
+	for _, tt := range tests {
+		v, err := s.eval(tt.expr)
+		if err != nil {
+			t.Errorf("eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := v.String(); got != tt.want {
+			t.Errorf("eval(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	s := &scope{
+		defs: map[string]interface{}{
+			"self": "${self} + 1s",
+			"a":    "${b}",
+			"b":    "${a}",
+			"list": []interface{}{1},
+		},
+		values:    map[string]value{},
+		resolving: map[string]bool{},
+	}
+
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "unexpected end of expression"},
+		{"1m +", "unexpected end of expression"},
+		{"(1m + 2s", "missing closing parenthesis"},
+		{"1m 2s", `unexpected "2s"`},
+		{"1m * 2s", "cannot multiply two durations"},
+		{"1m / 0", "division by zero"},
+		{"1m / (1s - 1s)", "division by zero"},
+		{"2 / 1s", "cannot divide a number by a duration"},
+		{"3weeks", `invalid duration "3weeks"`},
+		{"1.2.3", `invalid number "1.2.3"`},
+		{"1m % 2", `unexpected "% 2"`},
+		{"${nope}", `unknown variable "nope"`},
+		{"${work", "unterminated variable reference"},
+		{"${self}", `variable "self" references itself`},
+		{"${a}", `variable "a" references itself`},
+		{"${list}", "invalid value [1]"},
+	}
+
+	for _, tt := range tests {
+		_, err := s.eval(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("eval(%q): err = %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestVars(t *testing.T) {
+	content := `
+vars:
+  work: 40s
+  rounds: 8
+sections:
+  - name: Work ${rounds}x${work}
+    duration: ${work} * 2
+  - name: Rest
+    duration: (${work} * ${rounds}) / 16
+`
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		sections []Section
+	}{
+		{"declared", nil, []Section{
+			{Name: "Work 8x40s", Duration: 80 * time.Second},
+			{Name: "Rest", Duration: 20 * time.Second},
+		}},
+		{"overridden", map[string]string{"work": "1m", "rounds": "4"}, []Section{
+			{Name: "Work 4x1m0s", Duration: 2 * time.Minute},
+			{Name: "Rest", Duration: 15 * time.Second},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, doc, err := ParseWithOptions(strings.NewReader(content), Options{Vars: tt.vars})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, s := range doc.Sections {
+				if s.Name != tt.sections[i].Name || s.Duration != tt.sections[i].Duration {
+					t.Errorf("section %d = %s %v, want %s %v", i+1, s.Name, s.Duration, tt.sections[i].Name, tt.sections[i].Duration)
+				}
+			}
+		})
+	}
+}
+
+func TestDurationErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"sections: [{name: A, duration: 10s - 20s}]", `section 1: "A" must last longer than 0s, its duration is -10s`},
+		{"sections: [{name: A, duration: 1s}, {name: B, duration: 0s}]", `section 2: "B" must last longer than 0s, its duration is 0s`},
+		{"vars: {w: 10s}\nsections: [{name: A, duration: '${w} - 10s'}]", `section 1: "A" must last longer than 0s`},
+		{"sections: [{name: A, duration: -5}]", `section 1: "A" must last longer than 0s, its duration is -5s`},
+		{"sections: [{name: G, sections: [{name: A, duration: -1m}]}]", `section 1: section 1: "A" must last longer than 0s`},
+		{"sections: [{name: A, duration: 1m +}]", `section 1: invalid duration "1m +": unexpected end of expression`},
+		{"sections: [{name: A, duration: 0s..30s}]", `section 1: invalid duration "0s..30s": the lower bound must be longer than 0s`},
+		{"sections: [{name: A, duration: 10s - 20s..30s}]", "the lower bound must be longer than 0s"},
+		{"sections: [{name: A, duration: 40s..20s}]", "the upper bound is lower than the lower bound"},
+		{"prestart: 1s * 1s\nsections: [{name: A, duration: 1s}]", "prestart: invalid duration"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Parse(strings.NewReader(tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q): err = %v, want %q", tt.content, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+// Checkpoint is where a session stands, saved while
+// it is played so that it can be resumed
+type Checkpoint struct {
+	// Document, Hash and Raw describe the played document as in
+	// the Session. Raw is parsed again if Document is not a file.
+	Document string `json:"document"`
+	Hash     string `json:"hash"`
+	Raw      string `json:"raw"`
+	// Vars are the variables the document was read with
+	Vars  map[string]string `json:"vars,omitempty"`
+	Saved time.Time         `json:"saved"`
+	// Round is the loop iteration, starting at 1
+	Round int `json:"round"`
+	// Index is the position of the current section in the round, starting at 0
+	Index int `json:"index"`
+	// Remaining is the time left in the current section
+	Remaining time.Duration `json:"remaining"`
+	// Sections are the sections of the round as they were planned,
+	// random durations and shuffled sections included
+	Sections []document.Section `json:"sections"`
+}
+
+// Section returns the current section of the checkpoint
+func (o Checkpoint) Section() (document.Section, bool) {
+	if o.Index < 0 || o.Index >= len(o.Sections) {
+		return document.Section{}, false
+	}
+	return o.Sections[o.Index], true
+}
+
+// CheckpointFile returns the path of the checkpoint file
+func CheckpointFile() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "checkpoint.json"), nil
+}
+
+// SaveCheckpoint replaces the checkpoint file by c. The file is
+// written aside and renamed so that a crash cannot corrupt it.
+func SaveCheckpoint(c Checkpoint) error {
+	file, err := CheckpointFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// ReadCheckpoint returns the saved checkpoint, nil if there is none
+func ReadCheckpoint() (*Checkpoint, error) {
+	file, err := CheckpointFile()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return &c, nil
+}
+
+// RemoveCheckpoint removes the checkpoint file if it exists
+func RemoveCheckpoint() error {
+	file, err := CheckpointFile()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
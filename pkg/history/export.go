@@ -0,0 +1,214 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is a file format the sessions are exported to
+type ExportFormat string
+
+const (
+	// CSV lists a session per line, for spreadsheets
+	CSV ExportFormat = "csv"
+	// ICS is an iCalendar file with an event per session
+	ICS ExportFormat = "ics"
+	// TCX is a Training Center XML file with an activity per
+	// session and a lap per section, for fitness trackers
+	TCX ExportFormat = "tcx"
+)
+
+// ParseExportFormat returns the export format matching name (case
+// insensitive). "ical" is accepted as an alias of "ics".
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return CSV, nil
+	case "ics", "ical":
+		return ICS, nil
+	case "tcx":
+		return TCX, nil
+	}
+
+	return "", fmt.Errorf("unknown export format %q (available formats are csv, ics and tcx)", name)
+}
+
+// DetectExportFormat guesses the export format from the extension of file, CSV is the fallback
+func DetectExportFormat(file string) ExportFormat {
+	if f, err := ParseExportFormat(strings.TrimPrefix(filepath.Ext(file), ".")); err == nil {
+		return f
+	}
+	return CSV
+}
+
+// Export writes sessions to w in format
+func Export(w io.Writer, sessions []Session, format ExportFormat) error {
+	switch format {
+	case CSV:
+		return exportCSV(w, sessions)
+	case ICS:
+		return exportICS(w, sessions)
+	case TCX:
+		return exportTCX(w, sessions)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// seconds formats d as a number of seconds
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+func exportCSV(w io.Writer, sessions []Session) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"start", "end", "length_s", "trained_s", "completed", "sections", "pauses", "skips", "document", "hash"})
+	for _, s := range sessions {
+		out.Write([]string{
+			s.Start.Format(time.RFC3339),
+			s.End.Format(time.RFC3339),
+			seconds(s.End.Sub(s.Start).Round(time.Second)),
+			seconds(s.Trained().Round(time.Second)),
+			strconv.FormatBool(s.Completed),
+			strconv.Itoa(len(s.Sections)),
+			strconv.Itoa(s.Pauses()),
+			strconv.Itoa(s.Skips()),
+			s.Document,
+			s.Hash,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// icsTime is the UTC date-time format of iCalendar
+const icsTime = "20060102T150405Z"
+
+// icsEscape escapes the special characters of an iCalendar text value
+var icsEscape = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// icsLine returns a content line of an iCalendar file. Lines
+// longer than 75 bytes are folded, without splitting a character.
+func icsLine(name, value string) string {
+	line := name + ":" + value
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func exportICS(w io.Writer, sessions []Session) error {
+	var b strings.Builder
+	b.WriteString(icsLine("BEGIN", "VCALENDAR"))
+	b.WriteString(icsLine("VERSION", "2.0"))
+	b.WriteString(icsLine("PRODID", "-//bipper//bipper export//EN"))
+	stamp := time.Now().UTC().Format(icsTime)
+	for _, s := range sessions {
+		status := "aborted"
+		if s.Completed {
+			status = "completed"
+		}
+		var description strings.Builder
+		fmt.Fprintf(&description, "%s, trained %v", status, s.Trained().Round(time.Second))
+		for _, section := range s.Sections {
+			fmt.Fprintf(&description, "\n%s %v", section.Name, section.Actual.Round(time.Second))
+			if section.Skipped {
+				description.WriteString(" skipped")
+			}
+		}
+
+		b.WriteString(icsLine("BEGIN", "VEVENT"))
+		// The start time tells the sessions of a document apart
+		b.WriteString(icsLine("UID", fmt.Sprintf("%d-%.16s@bipper", s.Start.Unix(), s.Hash)))
+		b.WriteString(icsLine("DTSTAMP", stamp))
+		b.WriteString(icsLine("DTSTART", s.Start.UTC().Format(icsTime)))
+		b.WriteString(icsLine("DTEND", s.End.UTC().Format(icsTime)))
+		b.WriteString(icsLine("SUMMARY", icsEscape.Replace("bipper "+filepath.Base(s.Document))))
+		b.WriteString(icsLine("DESCRIPTION", icsEscape.Replace(description.String())))
+		b.WriteString(icsLine("END", "VEVENT"))
+	}
+	b.WriteString(icsLine("END", "VCALENDAR"))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// The elements of a Training Center XML file used by bipper,
+// in the order required by its schema
+type tcxDatabase struct {
+	XMLName    xml.Name      `xml:"TrainingCenterDatabase"`
+	Xmlns      string        `xml:"xmlns,attr"`
+	Activities []tcxActivity `xml:"Activities>Activity"`
+}
+
+type tcxActivity struct {
+	Sport string   `xml:"Sport,attr"`
+	ID    string   `xml:"Id"`
+	Laps  []tcxLap `xml:"Lap"`
+	Notes string   `xml:"Notes,omitempty"`
+}
+
+type tcxLap struct {
+	StartTime        string  `xml:"StartTime,attr"`
+	TotalTimeSeconds float64 `xml:"TotalTimeSeconds"`
+	DistanceMeters   float64 `xml:"DistanceMeters"`
+	Calories         int     `xml:"Calories"`
+	Intensity        string  `xml:"Intensity"`
+	TriggerMethod    string  `xml:"TriggerMethod"`
+	Notes            string  `xml:"Notes,omitempty"`
+}
+
+// exportTCX writes an activity per session. The time of a lap excludes
+// the pauses, a section is a lap started manually. Sessions without any
+// section are left out as an activity needs a lap.
+func exportTCX(w io.Writer, sessions []Session) error {
+	db := tcxDatabase{Xmlns: "http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2"}
+	for _, s := range sessions {
+		if len(s.Sections) == 0 {
+			continue
+		}
+
+		activity := tcxActivity{
+			Sport: "Other",
+			ID:    s.Start.UTC().Format(time.RFC3339),
+			Notes: s.Document,
+		}
+		start := s.Start
+		for _, section := range s.Sections {
+			activity.Laps = append(activity.Laps, tcxLap{
+				StartTime:        start.UTC().Format(time.RFC3339),
+				TotalTimeSeconds: (section.Actual - section.Paused).Seconds(),
+				Intensity:        "Active",
+				TriggerMethod:    "Manual",
+				Notes:            section.Name,
+			})
+			start = start.Add(section.Actual)
+		}
+		db.Activities = append(db.Activities, activity)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(db); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
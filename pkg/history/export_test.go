@@ -0,0 +1,136 @@
+package history
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+func TestICSLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		lines int
+	}{
+		{"short", "VCALENDAR", 1},
+		{"exactly 75 octets", strings.Repeat("a", 75-len("SUMMARY:")), 1},
+		{"76 octets", strings.Repeat("a", 76-len("SUMMARY:")), 2},
+		{"long", strings.Repeat("abcdefghij", 20), 3},
+		{"multibyte", strings.Repeat("é", 100), 3},
+		{"emoji", strings.Repeat("💪", 40), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := icsLine("SUMMARY", tt.value)
+			if !strings.HasSuffix(line, "\r\n") {
+				t.Fatalf("%q does not end with CRLF", line)
+			}
+
+			folded := strings.Split(strings.TrimSuffix(line, "\r\n"), "\r\n")
+			if len(folded) != tt.lines {
+				t.Errorf("%d lines, want %d", len(folded), tt.lines)
+			}
+			for i, l := range folded {
+				if len(l) > 75 {
+					t.Errorf("line %d is %d octets long", i+1, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a character: %q", i+1, l)
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("line %d does not start with a space: %q", i+1, l)
+				}
+			}
+
+			unfolded := strings.Replace(strings.TrimSuffix(line, "\r\n"), "\r\n ", "", -1)
+			if want := "SUMMARY:" + tt.value; unfolded != want {
+				t.Errorf("unfolded = %q, want %q", unfolded, want)
+			}
+		})
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain text", "plain text"},
+		{"a, b; c", `a\, b\; c`},
+		{`C:\plans`, `C:\\plans`},
+		{"two\nlines", `two\nlines`},
+		{`\n`, `\\n`},
+	}
+
+	for _, tt := range tests {
+		if got := icsEscape.Replace(tt.text); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	start := time.Date(2024, 5, 15, 18, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	sessions := []Session{{
+		Document:  "/plans/legs, glutes; core.yaml",
+		Hash:      Hash(document.Document{}),
+		Start:     start,
+		End:       start.Add(10 * time.Minute),
+		Completed: true,
+		Sections: []Section{
+			{Name: "Squats", Actual: 5 * time.Minute, Paused: time.Minute},
+			{Name: "Lunges", Actual: 5 * time.Minute, Skipped: true},
+		},
+	}}
+
+	var b bytes.Buffer
+	if err := Export(&b, sessions, ICS); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20240515T160000Z\r\n",
+		"DTEND:20240515T161000Z\r\n",
+		`SUMMARY:bipper legs\, glutes\; core.yaml` + "\r\n",
+		`DESCRIPTION:completed\, trained 9m0s\nSquats 5m0s\nLunges 5m0s skipped` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q not found in\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.Replace(out, "\r\n", "", -1), "\n") {
+		t.Errorf("lines do not all end with CRLF:\n%q", out)
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want ExportFormat
+	}{
+		{"csv", CSV},
+		{"ICS", ICS},
+		{"ical", ICS},
+		{"tcx", TCX},
+	}
+	for _, tt := range tests {
+		if got, err := ParseExportFormat(tt.name); err != nil || got != tt.want {
+			t.Errorf("ParseExportFormat(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseExportFormat("xlsx"); err == nil {
+		t.Error("xlsx is accepted")
+	}
+	if got := DetectExportFormat("sessions.txt"); got != CSV {
+		t.Errorf("DetectExportFormat(sessions.txt) = %v, want csv", got)
+	}
+}
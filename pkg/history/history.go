@@ -0,0 +1,158 @@
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+// Session is the record of a played document
+type Session struct {
+	// Document is the absolute path of the document, or
+	// a description such as "preset tabata" if it has none
+	Document string `json:"document"`
+	// Hash is the SHA-256 of the expanded document, see Hash
+	Hash      string    `json:"hash"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Sections  []Section `json:"sections"`
+	Completed bool      `json:"completed"`
+}
+
+// Section is the record of a played section
+type Section struct {
+	Name  string        `json:"name"`
+	Kind  document.Kind `json:"kind,omitempty"`
+	Round int           `json:"round"`
+	// Planned is the duration of the section in the document,
+	// Actual the time spent in the section, pauses included
+	Planned time.Duration `json:"planned"`
+	Actual  time.Duration `json:"actual"`
+	Paused  time.Duration `json:"paused"`
+	Pauses  int           `json:"pauses"`
+	// Skipped is true if the section was left before its end
+	Skipped bool `json:"skipped"`
+}
+
+// Hash returns the hash of a document once expanded, so that the
+// changes of the documents it includes and of its vars count. The
+// prestart countdown, which is set from the command line too, does not.
+func Hash(doc document.Document) string {
+	doc.Prestart = 0
+	// Encoding a document to JSON cannot fail
+	content, _ := document.Marshal(doc, document.JSON)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Pauses returns the number of pauses of the session
+func (o Session) Pauses() int {
+	n := 0
+	for _, s := range o.Sections {
+		n += s.Pauses
+	}
+	return n
+}
+
+// Skips returns the number of skipped sections of the session
+func (o Session) Skips() int {
+	n := 0
+	for _, s := range o.Sections {
+		if s.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// Dir returns the directory of the data files, bipper in
+// $XDG_DATA_HOME or in ~/.local/share if it is not set
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "bipper"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "bipper"), nil
+}
+
+// File returns the path of the history file. Sessions
+// are stored as JSON objects, one per line.
+func File() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Append adds s at the end of the history
+func Append(s Session) error {
+	file, err := File()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the sessions of the history, oldest first.
+// There is no session if the history file does not exist.
+func Read() ([]Session, error) {
+	file, err := File()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sessions []Session
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<24)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var s Session
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, scanner.Err()
+}
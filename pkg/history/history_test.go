@@ -0,0 +1,32 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+func TestHash(t *testing.T) {
+	doc := document.Document{Sections: []document.Section{
+		{Name: "Work", Duration: 40 * time.Second},
+		{Name: "Rest", Duration: 20 * time.Second},
+	}}
+	hash := Hash(doc)
+
+	prestart := doc
+	prestart.Prestart = 10 * time.Second
+	if Hash(prestart) != hash {
+		t.Error("the prestart countdown changes the hash")
+	}
+
+	// An included document or a var changes the durations of the expanded document
+	changed := doc
+	changed.Sections = []document.Section{doc.Sections[0], {Name: "Rest", Duration: 30 * time.Second}}
+	if Hash(changed) == hash {
+		t.Error("a changed duration keeps the hash")
+	}
+	if Hash(document.Document{Sections: doc.Sections[:1]}) == hash {
+		t.Error("a removed section keeps the hash")
+	}
+}
@@ -0,0 +1,241 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+// Period is the training of a day, a week or a month
+type Period struct {
+	Start    time.Time
+	Trained  time.Duration
+	Sessions int
+}
+
+// Plan is the training of a document
+type Plan struct {
+	Document string
+	Trained  time.Duration
+	Sessions int
+}
+
+// Stats sums the sessions of the history up
+type Stats struct {
+	Sessions int
+	Trained  time.Duration
+	// Days are the last days, today included, oldest first
+	Days []Period
+	// Weeks and Months are the periods with a session, oldest first
+	Weeks  []Period
+	Months []Period
+	// Plans are sorted by number of sessions, most played first
+	Plans []Plan
+	// CurrentStreak is the number of consecutive days with a session up to
+	// today, or up to yesterday if there is no session today yet
+	CurrentStreak int
+	LongestStreak int
+}
+
+// Trained returns the time spent in the sections of the session, pauses
+// and prep sections, such as waiting for a section due at a time, excluded
+func (o Session) Trained() time.Duration {
+	var d time.Duration
+	for _, s := range o.Sections {
+		if s.Kind != document.KindPrep {
+			d += s.Actual - s.Paused
+		}
+	}
+	return d
+}
+
+// day returns the midnight starting the day of t
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// week returns the midnight starting the week of t, on Monday
+func week(t time.Time) time.Time {
+	d := day(t)
+	return d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+}
+
+func month(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// Compute returns the stats of sessions in the time zone of now,
+// days is the number of days listed in Stats.Days
+func Compute(sessions []Session, now time.Time, days int) Stats {
+	var st Stats
+	loc := now.Location()
+
+	trainedDays := map[time.Time]*Period{}
+	weeks := map[time.Time]*Period{}
+	months := map[time.Time]*Period{}
+	plans := map[string]*Plan{}
+	add := func(periods map[time.Time]*Period, start time.Time, trained time.Duration) {
+		p, ok := periods[start]
+		if !ok {
+			p = &Period{Start: start}
+			periods[start] = p
+		}
+		p.Trained += trained
+		p.Sessions++
+	}
+
+	for _, s := range sessions {
+		start := s.Start.In(loc)
+		trained := s.Trained()
+		st.Sessions++
+		st.Trained += trained
+
+		add(trainedDays, day(start), trained)
+		add(weeks, week(start), trained)
+		add(months, month(start), trained)
+
+		p, ok := plans[s.Document]
+		if !ok {
+			p = &Plan{Document: s.Document}
+			plans[s.Document] = p
+		}
+		p.Trained += trained
+		p.Sessions++
+	}
+
+	today := day(now)
+	for i := days - 1; i >= 0; i-- {
+		d := today.AddDate(0, 0, -i)
+		p := Period{Start: d}
+		if trained, ok := trainedDays[d]; ok {
+			p = *trained
+		}
+		st.Days = append(st.Days, p)
+	}
+	st.Weeks = sortPeriods(weeks)
+	st.Months = sortPeriods(months)
+
+	for _, p := range plans {
+		st.Plans = append(st.Plans, *p)
+	}
+	sort.Slice(st.Plans, func(i, j int) bool {
+		if st.Plans[i].Sessions != st.Plans[j].Sessions {
+			return st.Plans[i].Sessions > st.Plans[j].Sessions
+		}
+		return st.Plans[i].Document < st.Plans[j].Document
+	})
+
+	st.CurrentStreak, st.LongestStreak = streaks(trainedDays, today)
+	return st
+}
+
+func sortPeriods(periods map[time.Time]*Period) []Period {
+	var sorted []Period
+	for _, p := range periods {
+		sorted = append(sorted, *p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
+
+// streaks returns the current and the longest daily streaks
+func streaks(days map[time.Time]*Period, today time.Time) (current, longest int) {
+	var sorted []time.Time
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+
+	run := 0
+	for i, d := range sorted {
+		// Days are compared by date as a day does not last 24
+		// hours when the daylight saving time changes
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(d) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+
+	// The streak is not broken until today is over
+	d := today
+	if _, ok := days[d]; !ok {
+		d = d.AddDate(0, 0, -1)
+	}
+	for {
+		if _, ok := days[d]; !ok {
+			return current, longest
+		}
+		current++
+		d = d.AddDate(0, 0, -1)
+	}
+}
+
+// chartWidth is the number of characters of the longest bar of the chart
+const chartWidth = 40
+
+// Report formats the stats as text. It ends with at most
+// periods weeks and months.
+func (o Stats) Report(periods int) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Sessions %d, trained %v\n", o.Sessions, o.Trained.Round(time.Second))
+	fmt.Fprintf(&b, "Current streak %s, longest streak %s\n", plural(o.CurrentStreak, "day"), plural(o.LongestStreak, "day"))
+
+	var max time.Duration
+	for _, d := range o.Days {
+		if d.Trained > max {
+			max = d.Trained
+		}
+	}
+	fmt.Fprintf(&b, "\nLast %s\n", plural(len(o.Days), "day"))
+	for _, d := range o.Days {
+		bar := 0
+		if max > 0 {
+			bar = int(int64(chartWidth) * int64(d.Trained) / int64(max))
+		}
+		fmt.Fprintf(&b, "%s %-*s %v\n", d.Start.Format("Mon 01-02"), chartWidth, strings.Repeat("#", bar), d.Trained.Round(time.Second))
+	}
+
+	last := func(p []Period) []Period {
+		if len(p) > periods {
+			return p[len(p)-periods:]
+		}
+		return p
+	}
+	b.WriteString("\nWeeks\n")
+	for _, w := range last(o.Weeks) {
+		y, n := w.Start.ISOWeek()
+		fmt.Fprintf(&b, "%d-W%02d  %-10v %s\n", y, n, w.Trained.Round(time.Second), plural(w.Sessions, "session"))
+	}
+	b.WriteString("\nMonths\n")
+	for _, m := range last(o.Months) {
+		fmt.Fprintf(&b, "%s  %-10v %s\n", m.Start.Format("2006-01"), m.Trained.Round(time.Second), plural(m.Sessions, "session"))
+	}
+
+	b.WriteString("\nPlans\n")
+	for _, p := range o.Plans {
+		fmt.Fprintf(&b, "%-12s %-10v %s\n", plural(p.Sessions, "session"), p.Trained.Round(time.Second), p.Document)
+	}
+
+	return b.String()
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
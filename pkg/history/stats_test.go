@@ -0,0 +1,99 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+func TestStreaks(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	// The clocks move forward on the night of March 30th 2024 in Paris
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, paris)
+	}
+
+	tests := []struct {
+		name     string
+		starts   []time.Time
+		now      time.Time
+		current  int
+		longest  int
+		sessions int
+	}{
+		{"no session", nil, at(28, 12, 0), 0, 0, 0},
+		{"today only", []time.Time{at(28, 8, 0)}, at(28, 12, 0), 1, 1, 1},
+		{"yesterday keeps the streak", []time.Time{at(26, 8, 0), at(27, 8, 0)}, at(28, 12, 0), 2, 2, 2},
+		{"broken the day before yesterday", []time.Time{at(25, 8, 0), at(26, 8, 0)}, at(28, 12, 0), 0, 2, 2},
+		{"twice a day counts once", []time.Time{at(27, 8, 0), at(27, 20, 0), at(28, 7, 0)}, at(28, 12, 0), 2, 2, 3},
+		{"around midnight", []time.Time{at(27, 23, 59), at(28, 0, 1)}, at(28, 12, 0), 2, 2, 2},
+		{"longest in the past", []time.Time{at(20, 8, 0), at(21, 8, 0), at(22, 8, 0), at(27, 8, 0)}, at(28, 12, 0), 1, 3, 4},
+		{"across the clock change", []time.Time{at(30, 23, 30), at(31, 0, 30), at(31, 23, 30), at(1, 0, 30).AddDate(0, 1, 0)}, at(1, 12, 0).AddDate(0, 1, 0), 3, 3, 4},
+		// Sessions are counted on the day of the time zone of now
+		{"time zone of now", []time.Time{at(27, 23, 30).UTC(), at(28, 8, 0).UTC()}, at(28, 12, 0), 2, 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sessions []Session
+			for _, start := range tt.starts {
+				sessions = append(sessions, Session{Start: start})
+			}
+
+			st := Compute(sessions, tt.now, 7)
+			if st.CurrentStreak != tt.current || st.LongestStreak != tt.longest {
+				t.Errorf("streaks = %d %d, want %d %d", st.CurrentStreak, st.LongestStreak, tt.current, tt.longest)
+			}
+			if st.Sessions != tt.sessions {
+				t.Errorf("sessions = %d, want %d", st.Sessions, tt.sessions)
+			}
+			if len(st.Days) != 7 || !st.Days[6].Start.Equal(day(tt.now)) {
+				t.Errorf("days = %v, want 7 days up to %v", st.Days, day(tt.now))
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	session := func(document string, start time.Time, actual, paused time.Duration) Session {
+		return Session{Document: document, Start: start, Sections: []Section{
+			{Name: "Work", Actual: actual, Paused: paused},
+			{Name: "Rest", Actual: time.Minute},
+		}}
+	}
+	sessions := []Session{
+		session("a.yaml", now.AddDate(0, -1, 0), 10*time.Minute, 0),
+		session("b.yaml", now.AddDate(0, 0, -2), 10*time.Minute, 2*time.Minute),
+		session("b.yaml", now, 5*time.Minute, time.Minute),
+		// The wait for a section due at a time of the day is not training
+		{Document: "c.yaml", Start: now.AddDate(0, -1, 0), Sections: []Section{
+			{Name: "Waiting for Run", Kind: document.KindPrep, Actual: 20 * time.Hour},
+		}},
+	}
+
+	st := Compute(sessions, now, 3)
+	if want := 11*time.Minute + 9*time.Minute + 5*time.Minute; st.Trained != want {
+		t.Errorf("trained = %v, want %v", st.Trained, want)
+	}
+
+	days := []time.Duration{9 * time.Minute, 0, 5 * time.Minute}
+	for i, d := range st.Days {
+		if d.Trained != days[i] {
+			t.Errorf("day %d trained %v, want %v", i, d.Trained, days[i])
+		}
+	}
+	if len(st.Weeks) != 2 || st.Weeks[1].Sessions != 2 || st.Weeks[1].Start.Weekday() != time.Monday {
+		t.Errorf("weeks = %+v, want 2 weeks starting on Monday", st.Weeks)
+	}
+	if len(st.Months) != 2 || st.Months[1].Sessions != 2 || st.Months[1].Start.Day() != 1 {
+		t.Errorf("months = %+v, want 2 months", st.Months)
+	}
+	if len(st.Plans) != 3 || st.Plans[0].Document != "b.yaml" || st.Plans[0].Trained != 14*time.Minute {
+		t.Errorf("plans = %+v, want b.yaml first", st.Plans)
+	}
+}
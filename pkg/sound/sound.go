@@ -3,22 +3,84 @@ package sound
 import (
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
 	"github.com/faiface/beep/mp3"
 	"github.com/faiface/beep/speaker"
 )
 
+// MaxVolume is the volume the sounds are played at by default
+const MaxVolume = 10
+
+// The volume is shared by every player as they use the same speaker
+var (
+	volumeMu sync.Mutex
+	volume   = MaxVolume
+	muted    bool
+)
+
+// SetVolume sets the volume, from 1 to MaxVolume
+func SetVolume(v int) {
+	volumeMu.Lock()
+	defer volumeMu.Unlock()
+
+	if v < 1 {
+		v = 1
+	} else if v > MaxVolume {
+		v = MaxVolume
+	}
+	volume = v
+}
+
+// VolumeUp raises the volume by one step and returns the new volume
+func VolumeUp() int {
+	volumeMu.Lock()
+	defer volumeMu.Unlock()
+
+	if volume < MaxVolume {
+		volume++
+	}
+	return volume
+}
+
+// VolumeDown lowers the volume by one step and returns the new volume.
+// The lowest volume is 1, use ToggleMute to silence the sounds.
+func VolumeDown() int {
+	volumeMu.Lock()
+	defer volumeMu.Unlock()
+
+	if volume > 1 {
+		volume--
+	}
+	return volume
+}
+
+// ToggleMute silences or restores the sounds, true is
+// returned if the sounds are muted
+func ToggleMute() bool {
+	volumeMu.Lock()
+	defer volumeMu.Unlock()
+
+	muted = !muted
+	return muted
+}
+
 type Player interface {
 	Read(file string)
 	Play()
 	Close()
 }
 
+// BeepPlayer is safe for concurrent use
 type BeepPlayer struct {
 	streamer beep.StreamSeekCloser
 	format beep.Format
+	// mu serialises the plays, closed is true once Close is called
+	mu sync.Mutex
+	closed bool
 }
 
 func NewPlayer() Player {
@@ -40,12 +102,27 @@ func (o *BeepPlayer) Read(file string) {
 }
 
 func (o *BeepPlayer) Play() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+
+	volumeMu.Lock()
+	// Every step multiplies or divides the gain by √2
+	v := &effects.Volume{Streamer: o.streamer, Base: 2, Volume: float64(volume-MaxVolume) / 2, Silent: muted}
+	volumeMu.Unlock()
+
 	// Play whole stream at each call
 	speaker.Clear()
 	o.streamer.Seek(0)
-	speaker.Play(o.streamer)
+	speaker.Play(v)
 }
 
 func (o *BeepPlayer) Close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
 	o.streamer.Close()
 }
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/syncro"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+	"github.com/mum4k/termdash/widgets/textinput"
+)
+
+// fileInput is the file path field. It records whether it is
+// focused so that the key shortcuts are ignored while typing.
+type fileInput struct {
+	*textinput.TextInput
+	focused *syncro.AtomicBool
+}
+
+// Draw implements widgetapi.Widget.Draw.
+func (o *fileInput) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	if meta.Focused {
+		o.focused.True()
+	} else {
+		o.focused.False()
+	}
+	return o.TextInput.Draw(cvs, meta)
+}
+
+// documentExtensions are the extensions of the files listed by the browser
+var documentExtensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// browserView is what the file browser displays
+type browserView struct {
+	dir    string
+	filter string
+}
+
+// documentEntry is a document of the plans directory
+type documentEntry struct {
+	path string
+	doc  document.Document
+	err  error
+}
+
+// listDocuments returns the documents of dir whose file name
+// contains filter, case insensitive
+func listDocuments(dir, filter string, opts document.Options) ([]documentEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	filter = strings.ToLower(filter)
+	var entries []documentEntry
+	for _, f := range files {
+		if f.IsDir() || !isDocument(f.Name()) || !strings.Contains(strings.ToLower(f.Name()), filter) {
+			continue
+		}
+
+		e := documentEntry{path: filepath.Join(dir, f.Name())}
+		_, e.doc, e.err = document.ReadWithOptions(e.path, opts)
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func isDocument(name string) bool {
+	for _, ext := range documentExtensions {
+		if strings.EqualFold(filepath.Ext(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDocument returns the file to open when text is submitted: text
+// itself if it is an existing path, else the first document of dir
+// matching text. text is returned as is if nothing matches so that
+// opening it reports the error.
+func resolveDocument(dir, text string, opts document.Options) string {
+	if _, err := os.Stat(text); err == nil {
+		return text
+	}
+
+	entries, err := listDocuments(dir, text, opts)
+	if err != nil || len(entries) == 0 {
+		return text
+	}
+	return entries[0].path
+}
+
+// total returns the total duration of a document as displayed by the browser
+func total(doc document.Document) string {
+	t := doc.Total.String()
+	if doc.MaxTotal != doc.Total {
+		t += ".." + doc.MaxTotal.String()
+	}
+	if doc.Scheduled {
+		t = "~" + t
+	}
+	return t
+}
+
+// newBrowserText creates a new Text widget listing the documents
+// of a directory with their total duration
+func newBrowserText(ch chan browserView, opts document.Options, th theme) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			v := <-ch
+			t.Reset()
+
+			write := func(txt string, color cell.Color) {
+				if err := t.Write(printable(txt), text.WriteCellOpts(cell.FgColor(color))); err != nil {
+					panic(err)
+				}
+			}
+
+			entries, err := listDocuments(v.dir, v.filter, opts)
+			if err != nil {
+				write(err.Error(), th.invalid)
+				continue
+			}
+			if len(entries) == 0 {
+				write(fmt.Sprintf("No document matching %q in %s", v.filter, v.dir), th.text)
+				continue
+			}
+
+			// Submitting the filter opens the first document
+			for i, e := range entries {
+				prefix, color := "  ", th.text
+				if i == 0 {
+					prefix, color = "▶ ", th.valid
+				}
+
+				name := filepath.Base(e.path)
+				if e.err != nil {
+					write(fmt.Sprintf("%s%s invalid\n", prefix, name), th.invalid)
+					continue
+				}
+				write(fmt.Sprintf("%s%s %s\n", prefix, name, total(e.doc)), color)
+			}
+		}
+	}()
+
+	return t, nil
+}
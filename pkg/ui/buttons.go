@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"github.com/mum4k/termdash/container/grid"
+	"github.com/mum4k/termdash/widgets/button"
+)
+
+// buttonActions are the actions of the buttons, from left to right
+var buttonActions = []Action{ActionPrevious, ActionPause, ActionNext, ActionRestart}
+
+var buttonLabels = map[Action]string{
+	ActionPrevious: "Previous",
+	ActionPause:    "Pause",
+	ActionNext:     "Next",
+	ActionRestart:  "Restart",
+}
+
+// newButtons creates the buttons sending their action on ch when clicked
+func newButtons(ch chan<- Action, th theme) ([]*button.Button, error) {
+	var buttons []*button.Button
+	for _, action := range buttonActions {
+		action := action
+		b, err := button.New(buttonLabels[action], func() error {
+			ch <- action
+			return nil
+		},
+			button.Height(1),
+			button.WidthFor(buttonLabels[ActionPrevious]),
+			button.FillColor(th.button),
+			button.TextColor(th.buttonText),
+			button.ShadowColor(th.dim),
+		)
+		if err != nil {
+			return nil, err
+		}
+		buttons = append(buttons, b)
+	}
+
+	return buttons, nil
+}
+
+// buttonColumns lays the buttons out side by side
+func buttonColumns(buttons []*button.Button) []grid.Element {
+	var columns []grid.Element
+	for _, b := range buttons {
+		columns = append(columns, grid.ColWidthPerc(100/len(buttons), grid.Widget(b)))
+	}
+	return columns
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"strconv"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/mum4k/termdash/cell"
+)
+
+// Themes lists the names of the color themes
+var Themes = []string{"default", "high-contrast", "deuteranopia-safe", "monochrome"}
+
+// theme holds the colors of the UI
+type theme struct {
+	// text is the color of the labels and of the sections to come
+	text cell.Color
+	// dim is the color of the completed sections
+	dim cell.Color
+	// input is the background of the file path field
+	input  cell.Color
+	status cell.Color
+	// display is the color of the pause indicator
+	display cell.Color
+	// section and donut are used when a section has neither a kind nor a color
+	section cell.Color
+	donut   cell.Color
+	kinds   map[document.Kind]cell.Color
+	// running is the color of the countdown, ending
+	// replaces it during the last 3 seconds
+	running cell.Color
+	ending  cell.Color
+	// valid and invalid are the colors of the documents in the browser
+	valid   cell.Color
+	invalid cell.Color
+	cursor  cell.Color
+	// button and buttonText are the fill and text colors of the buttons
+	button     cell.Color
+	buttonText cell.Color
+	// documentColors is false when the colors of the sections are ignored
+	documentColors bool
+	// basic is true when the terminal is limited to 8 colors
+	basic bool
+}
+
+var themes = map[string]theme{
+	"default": {
+		text:    cell.ColorWhite,
+		dim:     cell.ColorNumber(243),
+		input:   cell.ColorNumber(0),
+		status:  cell.ColorYellow,
+		display: cell.ColorNumber(200),
+		section: cell.ColorNumber(200),
+		donut:   cell.ColorGreen,
+		kinds: map[document.Kind]cell.Color{
+			document.KindWork: cell.ColorNumber(202),
+			document.KindRest: cell.ColorNumber(39),
+			document.KindPrep: cell.ColorYellow,
+		},
+		running:        cell.ColorGreen,
+		ending:         cell.ColorRed,
+		valid:          cell.ColorGreen,
+		invalid:        cell.ColorRed,
+		cursor:         cell.ColorWhite,
+		button:         cell.ColorNumber(117),
+		buttonText:     cell.ColorBlack,
+		documentColors: true,
+	},
+	// Bright colors only, the countdown turns from white to yellow
+	"high-contrast": {
+		text:    cell.ColorNumber(231),
+		dim:     cell.ColorNumber(250),
+		input:   cell.ColorNumber(0),
+		status:  cell.ColorNumber(226),
+		display: cell.ColorNumber(231),
+		section: cell.ColorNumber(231),
+		donut:   cell.ColorNumber(231),
+		kinds: map[document.Kind]cell.Color{
+			document.KindWork: cell.ColorNumber(226),
+			document.KindRest: cell.ColorNumber(51),
+			document.KindPrep: cell.ColorNumber(231),
+		},
+		running:        cell.ColorNumber(231),
+		ending:         cell.ColorNumber(226),
+		valid:          cell.ColorNumber(226),
+		invalid:        cell.ColorNumber(196),
+		cursor:         cell.ColorNumber(231),
+		button:         cell.ColorNumber(231),
+		buttonText:     cell.ColorBlack,
+		documentColors: true,
+	},
+	// Blue and orange replace green and red, they are told
+	// apart by people who do not see green
+	"deuteranopia-safe": {
+		text:    cell.ColorWhite,
+		dim:     cell.ColorNumber(243),
+		input:   cell.ColorNumber(0),
+		status:  cell.ColorNumber(220),
+		display: cell.ColorNumber(117),
+		section: cell.ColorNumber(117),
+		donut:   cell.ColorNumber(33),
+		kinds: map[document.Kind]cell.Color{
+			document.KindWork: cell.ColorNumber(208),
+			document.KindRest: cell.ColorNumber(33),
+			document.KindPrep: cell.ColorNumber(220),
+		},
+		running:        cell.ColorNumber(33),
+		ending:         cell.ColorNumber(208),
+		valid:          cell.ColorNumber(33),
+		invalid:        cell.ColorNumber(208),
+		cursor:         cell.ColorWhite,
+		button:         cell.ColorNumber(117),
+		buttonText:     cell.ColorBlack,
+		documentColors: true,
+	},
+	// The terminal's foreground color is used everywhere
+	"monochrome": {
+		kinds:      map[document.Kind]cell.Color{},
+		button:     cell.ColorWhite,
+		buttonText: cell.ColorBlack,
+	},
+}
+
+// newTheme returns the theme named name, colors is the number of
+// colors of the terminal, either 8 or 256. The default theme is
+// returned if name is unknown.
+func newTheme(name string, colors int) theme {
+	t, ok := themes[name]
+	if !ok {
+		t = themes["default"]
+	}
+	if colors != 8 {
+		return t
+	}
+
+	t.basic = true
+	for _, c := range []*cell.Color{
+		&t.text, &t.dim, &t.input, &t.status, &t.display, &t.section,
+		&t.donut, &t.running, &t.ending, &t.valid, &t.invalid, &t.cursor,
+		&t.button, &t.buttonText,
+	} {
+		*c = basicColor(*c)
+	}
+	kinds := map[document.Kind]cell.Color{}
+	for k, c := range t.kinds {
+		kinds[k] = basicColor(c)
+	}
+	t.kinds = kinds
+
+	return t
+}
+
+// basicColor returns the closest of the 8 system colors to c
+func basicColor(c cell.Color) cell.Color {
+	if c <= cell.ColorWhite {
+		return c
+	}
+
+	// Colors are off-by-one due to ColorDefault being zero
+	n := int(c) - 1
+	switch {
+	case n < 16:
+		// Bright system colors
+		return cell.Color(n%8) + cell.ColorBlack
+	case n < 232:
+		// 6x6x6 cube, a component is on from its third level
+		n -= 16
+		r, g, b := n/36, n/6%6, n%6
+		i := 0
+		if r >= 2 {
+			i |= 1
+		}
+		if g >= 2 {
+			i |= 2
+		}
+		if b >= 2 {
+			i |= 4
+		}
+		return cell.Color(i) + cell.ColorBlack
+	case n < 240:
+		return cell.ColorBlack
+	default:
+		return cell.ColorWhite
+	}
+}
+
+// namedColors maps the document color names to terminal colors
+var namedColors = map[string]cell.Color{
+	"black":   cell.ColorBlack,
+	"red":     cell.ColorRed,
+	"green":   cell.ColorGreen,
+	"yellow":  cell.ColorYellow,
+	"blue":    cell.ColorBlue,
+	"magenta": cell.ColorMagenta,
+	"cyan":    cell.ColorCyan,
+	"white":   cell.ColorWhite,
+}
+
+// parseColor converts a document color to a terminal color
+func parseColor(c string) (cell.Color, bool) {
+	if color, ok := namedColors[c]; ok {
+		return color, true
+	}
+	if n, err := strconv.Atoi(c); err == nil {
+		return cell.ColorNumber(n), true
+	}
+	if len(c) == 7 && c[0] == '#' {
+		if rgb, err := strconv.ParseUint(c[1:], 16, 32); err == nil {
+			return cell.ColorRGB24(int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)), true
+		}
+	}
+
+	return cell.ColorDefault, false
+}
+
+// sectionColors returns the colors of the section's name and donut.
+// The section's color takes precedence over the color of its kind.
+func (o theme) sectionColors(s document.Section) (name, donut cell.Color) {
+	if color, ok := parseColor(s.Color); ok && o.documentColors {
+		if o.basic {
+			color = basicColor(color)
+		}
+		return color, color
+	}
+	if color, ok := o.kinds[s.Kind]; ok {
+		return color, color
+	}
+
+	return o.section, o.donut
+}
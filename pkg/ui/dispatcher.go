@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"image"
+	"sync"
+
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// Dispatcher is a hidden widget sending the action
+// bound to every key pressed on its channel
+type Dispatcher struct {
+	actions map[keyboard.Key]Action
+	ch      chan Action
+	// muted ignores the keys typed in a text field while it returns true
+	muted func() bool
+	// ignored ignores every key while it returns true
+	ignored func() bool
+
+	// mu protects the widget.
+	mu sync.Mutex
+}
+
+func NewDispatcher(b Bindings, ch chan Action) *Dispatcher {
+	d := &Dispatcher{}
+	d.actions = map[keyboard.Key]Action{}
+	for action, keys := range b {
+		for _, k := range keys {
+			d.actions[k] = action
+		}
+	}
+	d.ch = ch
+
+	return d
+}
+
+// MuteWhile ignores the keys typed in a text field while muted returns true
+func (o *Dispatcher) MuteWhile(muted func() bool) {
+	o.muted = muted
+}
+
+// IgnoreWhile ignores every key while ignored returns true,
+// another widget then handles the keyboard
+func (o *Dispatcher) IgnoreWhile(ignored func() bool) {
+	o.ignored = ignored
+}
+
+// Actions receives the action bound to every key pressed
+func (o *Dispatcher) Actions() chan Action {
+	return o.ch
+}
+
+// typingKeys are the special keys a text field handles
+var typingKeys = map[keyboard.Key]bool{
+	keyboard.KeyBackspace:  true,
+	keyboard.KeyBackspace2: true,
+	keyboard.KeyDelete:     true,
+	keyboard.KeyArrowLeft:  true,
+	keyboard.KeyArrowRight: true,
+	keyboard.KeyHome:       true,
+	keyboard.KeyEnd:        true,
+	keyboard.KeyCtrlA:      true,
+	keyboard.KeyCtrlE:      true,
+	keyboard.KeyEnter:      true,
+}
+
+////////////////////////////////////////////////////////////////////////////
+//
+// 					W I D G E T   I N T E R F A C E
+//
+////////////////////////////////////////////////////////////////////////////
+
+// When the infrastructure calls Draw(), the widget must block on the call
+// until it finishes drawing onto the provided canvas. When given the
+// canvas, the widget must first determine its size by calling
+// Canvas.Size(), then limit all its drawing to this area.
+//
+// The widget must not assume that the size of the canvas or its content
+// remains the same between calls.
+//
+// The argument meta is guaranteed to be valid (i.e. non-nil).
+// NOTE: THIS WIDGET DOESN'T DRAW ANYTHING
+func (o *Dispatcher) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	return nil
+}
+
+// action returns the action bound to the key of the keyboard event.
+func (o *Dispatcher) action(k *terminalapi.Keyboard) (Action, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.ignored != nil && o.ignored() {
+		return "", false
+	}
+	if o.muted != nil && o.muted() && (k.Key >= 0 || typingKeys[k.Key]) {
+		return "", false
+	}
+
+	action, ok := o.actions[k.Key]
+	return action, ok
+}
+
+// Keyboard processes keyboard events, sends the action bound to the key.
+//
+// Implements widgetapi.Widget.Keyboard.
+func (o *Dispatcher) Keyboard(k *terminalapi.Keyboard) error {
+	if action, ok := o.action(k); ok {
+		// Mutex must be released when sending the action.
+		// The receiver might call container methods like the
+		// Container.Update, see #205.
+		o.ch <- action
+	}
+	return nil
+}
+
+// Mouse is called when the widget is focused on the dashboard and a mouse
+// event happens on its canvas. Only called if the widget registered for mouse
+// events.
+func (o *Dispatcher) Mouse(m *terminalapi.Mouse) error {
+	return nil
+}
+
+// Options returns registration options for the widget.
+// This is how the widget indicates to the infrastructure whether it is
+// interested in keyboard or mouse shortcuts, what is its minimum canvas
+// size, etc.
+//
+// Most widgets will return statically compiled options (minimum and
+// maximum size, etc.). If the returned options depend on the runtime state
+// of the widget (e.g. the user data provided to the widget), the widget
+// must protect against a case where the infrastructure calls the Draw
+// method with a canvas that doesn't meet the requested options. This is
+// because the data in the widget might change between calls to Options and
+// Draw.
+func (o *Dispatcher) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{1, 1},
+		MaximumSize:  image.Point{1, 1},
+		WantKeyboard: widgetapi.KeyScopeGlobal,
+	}
+}
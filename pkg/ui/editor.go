@@ -0,0 +1,464 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/syncro"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/keyboard"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// newSectionDuration is the duration of the sections added in the editor
+const newSectionDuration = 30 * time.Second
+
+// editorField is the field being typed in the editor
+type editorField int
+
+const (
+	noField editorField = iota
+	nameField
+	durationField
+)
+
+// editorHelp lists the keys of the editor
+const editorHelp = "up/down select  a add  d delete  K/J move up/down  r rename  t duration  s save  esc close"
+
+// editor is the document editor panel. It handles the keyboard while
+// it is open, the dispatcher ignores every key in the meantime. The
+// top level sections of the document can be added, removed, moved,
+// renamed and given a new duration.
+type editor struct {
+	*text.Text
+	th theme
+	// actions receives ActionEdit when the editor must be closed
+	actions chan<- Action
+	status  chan<- string
+	// open is set by edit, it is reset by the receiver of actions
+	open *syncro.AtomicBool
+
+	// doc is the edited document, its totals are up to date
+	doc document.Document
+	// err is why doc cannot be saved, nil if it is valid
+	err error
+	// header are the comments kept at the top of the saved file
+	header string
+	// target is the file the document is saved to
+	target   string
+	selected int
+	modified bool
+	// closing is true once esc is pressed with unsaved changes
+	closing bool
+	field   editorField
+	input   []rune
+
+	// mu protects the fields above.
+	mu sync.Mutex
+}
+
+// newEditor creates the editor, open is true while it is open
+func newEditor(actions chan<- Action, status chan<- string, open *syncro.AtomicBool, th theme) (*editor, error) {
+	t, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return &editor{Text: t, th: th, actions: actions, status: status, open: open}, nil
+}
+
+// edit opens the editor on doc, saved to target after the header comments
+func (o *editor) edit(doc document.Document, header, target string) {
+	o.mu.Lock()
+	o.header = header
+	o.target = target
+	o.selected = 0
+	o.modified = false
+	o.closing = false
+	o.field = noField
+	o.setSections(doc, doc.Sections)
+	o.mu.Unlock()
+
+	o.open.True()
+	o.render()
+}
+
+// setSections replaces the sections of doc. The document is marshalled
+// and read again, as it would be once saved, to update its totals.
+func (o *editor) setSections(doc document.Document, sections []document.Section) {
+	doc.Sections = sections
+	o.doc = doc
+
+	content, err := document.Marshal(doc, document.YAML)
+	if err == nil {
+		o.doc, err = document.Unmarshal(content, document.YAML)
+	}
+	if err == nil {
+		err = validateDocument(o.doc)
+	}
+	o.err = err
+}
+
+// validateDocument returns why doc cannot be played, nil if it can
+func validateDocument(doc document.Document) error {
+	if len(doc.Sections) == 0 {
+		return fmt.Errorf("the document has no section")
+	}
+	return validateSections(doc.Sections, "")
+}
+
+// validateSections checks sections and the sections of their groups,
+// prefix is the position of the group holding them, such as "2."
+func validateSections(sections []document.Section, prefix string) error {
+	for i, s := range sections {
+		position := fmt.Sprintf("%s%d", prefix, i+1)
+		if strings.TrimSpace(s.Name) == "" {
+			return fmt.Errorf("section %s has no name", position)
+		}
+		if s.IsGroup() {
+			if err := validateSections(s.Sections, position+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if s.Duration <= 0 && s.Until == nil {
+			return fmt.Errorf("%s has no duration", s.Name)
+		}
+	}
+	return nil
+}
+
+// Keyboard implements widgetapi.Widget.Keyboard.
+func (o *editor) Keyboard(k *terminalapi.Keyboard) error {
+	if !o.open.Value() {
+		return nil
+	}
+
+	o.mu.Lock()
+	var msg string
+	closed := false
+	if o.field != noField {
+		msg = o.typeKey(k.Key)
+	} else {
+		msg, closed = o.command(k.Key)
+	}
+	o.mu.Unlock()
+
+	// Channels are written without holding the lock,
+	// their readers might draw the editor
+	if closed {
+		o.actions <- ActionEdit
+		return nil
+	}
+	o.render()
+	if msg != "" {
+		o.status <- msg
+	}
+	return nil
+}
+
+// command runs the command bound to k, it returns a message for
+// the status line and whether the editor must be closed
+func (o *editor) command(k keyboard.Key) (msg string, closed bool) {
+	closing := o.closing
+	o.closing = false
+
+	sections := append([]document.Section(nil), o.doc.Sections...)
+	i := o.selected
+
+	switch k {
+	case keyboard.KeyArrowUp, 'k':
+		if o.selected > 0 {
+			o.selected--
+		}
+	case keyboard.KeyArrowDown, 'j':
+		if o.selected < len(sections)-1 {
+			o.selected++
+		}
+
+	case 'a':
+		s := document.Section{Name: "New section", Duration: newSectionDuration}
+		if len(sections) == 0 {
+			i = -1
+		}
+		sections = append(sections[:i+1], append([]document.Section{s}, sections[i+1:]...)...)
+		o.selected = i + 1
+		o.change(sections)
+		o.field, o.input = nameField, nil
+
+	case 'd', keyboard.KeyDelete:
+		if len(sections) == 0 {
+			break
+		}
+		sections = append(sections[:i], sections[i+1:]...)
+		if o.selected >= len(sections) && o.selected > 0 {
+			o.selected--
+		}
+		o.change(sections)
+
+	case 'K':
+		if i > 0 && i < len(sections) {
+			sections[i-1], sections[i] = sections[i], sections[i-1]
+			o.selected--
+			o.change(sections)
+		}
+	case 'J':
+		if i < len(sections)-1 {
+			sections[i], sections[i+1] = sections[i+1], sections[i]
+			o.selected++
+			o.change(sections)
+		}
+
+	case 'r', keyboard.KeyEnter:
+		if len(sections) > 0 {
+			o.field, o.input = nameField, []rune(sections[i].Name)
+		}
+	case 't':
+		if len(sections) == 0 {
+			break
+		}
+		if sections[i].IsGroup() {
+			return "A group lasts as long as its sections", false
+		}
+		o.field, o.input = durationField, []rune(sections[i].Duration.String())
+
+	case 's', keyboard.KeyCtrlS:
+		return o.save(), false
+
+	case keyboard.KeyEsc:
+		if o.modified && !closing {
+			o.closing = true
+			return "Unsaved changes, press esc again to close the editor", false
+		}
+		return "", true
+	}
+
+	return "", false
+}
+
+// typeKey types k in the field being edited, enter sets the field
+// and esc cancels. It returns a message for the status line.
+func (o *editor) typeKey(k keyboard.Key) string {
+	switch {
+	case k == keyboard.KeyEsc:
+		o.field = noField
+	case k == keyboard.KeyBackspace || k == keyboard.KeyBackspace2:
+		if len(o.input) > 0 {
+			o.input = o.input[:len(o.input)-1]
+		}
+	case k == keyboard.KeyEnter:
+		return o.setField()
+	case k >= keyboard.KeySpace:
+		o.input = append(o.input, rune(k))
+	}
+	return ""
+}
+
+// setField sets the field typed in the selected section
+func (o *editor) setField() string {
+	value := strings.TrimSpace(string(o.input))
+	sections := append([]document.Section(nil), o.doc.Sections...)
+	s := &sections[o.selected]
+
+	switch o.field {
+	case nameField:
+		if value == "" {
+			return "A section needs a name"
+		}
+		s.Name = value
+
+	case durationField:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Sprintf("Invalid duration %q (expected 1m30s for instance)", value)
+		}
+		// The duration replaces a random range and a wall clock end
+		s.Duration = d
+		s.MaxDuration = 0
+		s.Until = nil
+	}
+
+	o.field = noField
+	o.change(sections)
+	return ""
+}
+
+// change replaces the sections of the edited document
+func (o *editor) change(sections []document.Section) {
+	o.setSections(o.doc, sections)
+	o.modified = true
+}
+
+// save writes the edited document as YAML to the target file
+func (o *editor) save() string {
+	if o.err != nil {
+		return fmt.Sprintf("Cannot save: %v", o.err)
+	}
+
+	content, err := document.Marshal(o.doc, document.YAML)
+	if err != nil {
+		return fmt.Sprintf("Cannot save: %v", err)
+	}
+	if err := ioutil.WriteFile(o.target, append([]byte(o.header), content...), 0644); err != nil {
+		return fmt.Sprintf("Cannot save: %v", err)
+	}
+
+	o.modified = false
+	return "Saved " + o.target
+}
+
+// render displays the edited document
+func (o *editor) render() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.Reset()
+	write := func(txt string, opts ...cell.Option) {
+		if err := o.Write(printable(txt), text.WriteCellOpts(opts...)); err != nil {
+			panic(err)
+		}
+	}
+
+	modified := ""
+	if o.modified {
+		modified = " (modified)"
+	}
+	write(fmt.Sprintf("%s%s\n", o.target, modified), cell.FgColor(o.th.dim))
+
+	total := fmt.Sprintf("Total %v", o.doc.Total)
+	if o.doc.MaxTotal > o.doc.Total {
+		total += fmt.Sprintf(" to %v", o.doc.MaxTotal)
+	}
+	if o.doc.Loop {
+		total += " per loop"
+	}
+	write(total+"\n\n", cell.FgColor(o.th.text))
+
+	width := 0
+	for _, s := range o.doc.Sections {
+		if n := len([]rune(s.Name)); n > width {
+			width = n
+		}
+	}
+	for i, s := range o.doc.Sections {
+		name := s.Name
+		if i == o.selected && o.field == nameField {
+			name = string(o.input) + "_"
+		}
+		duration := s.Duration.String()
+		if i == o.selected && o.field == durationField {
+			duration = string(o.input) + "_"
+		} else if s.MaxDuration > s.Duration {
+			duration += " to " + s.MaxDuration.String()
+		}
+		if s.IsGroup() {
+			duration = fmt.Sprintf("group of %d sections", len(s.Sections))
+		}
+
+		line := fmt.Sprintf("%-*s  %s\n", width, name, duration)
+		color, _ := o.th.sectionColors(s)
+		if i == o.selected {
+			write("▶ "+line, cell.FgColor(color))
+		} else {
+			write("  "+line, cell.FgColor(o.th.text))
+		}
+	}
+
+	if o.err != nil {
+		write(fmt.Sprintf("\n%v\n", o.err), cell.FgColor(o.th.invalid))
+	} else {
+		write("\n")
+	}
+	switch o.field {
+	case nameField:
+		write("\nType the name, enter to set it, esc to cancel", cell.FgColor(o.th.dim))
+	case durationField:
+		write("\nType the duration (1m30s), enter to set it, esc to cancel", cell.FgColor(o.th.dim))
+	default:
+		write("\n"+editorHelp, cell.FgColor(o.th.dim))
+	}
+}
+
+// Options implements widgetapi.Widget.Options.
+func (o *editor) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize:  image.Point{1, 1},
+		WantKeyboard: widgetapi.KeyScopeGlobal,
+	}
+}
+
+// headerComments returns the comment lines at the top of raw
+func headerComments(raw string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(raw, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && trimmed != "---" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			b.WriteString(trimmed + "\n")
+		}
+	}
+	return b.String()
+}
+
+// sourceDocument parses the document being played as it is written,
+// without the options of the command line such as -prestart. The file
+// is read again so that its includes are found, raw is parsed if the
+// document has no file.
+func sourceDocument(path, raw string) (document.Document, error) {
+	if filepath.IsAbs(path) {
+		_, doc, err := document.Read(path)
+		if !os.IsNotExist(err) {
+			return doc, err
+		}
+	}
+
+	_, doc, err := document.Parse(strings.NewReader(raw))
+	return doc, err
+}
+
+// editTarget returns the file an edited document is saved to. The
+// document's file is overwritten if it is written in YAML and uses
+// neither vars, templates, includes nor presets, which would be lost.
+// A new file is created next to it otherwise, or in the plans
+// directory if the document has no file.
+func editTarget(path, raw, plansDir string) string {
+	if !filepath.IsAbs(path) {
+		if dir, err := filepath.Abs(plansDir); err == nil {
+			plansDir = dir
+		}
+		return newFile(filepath.Join(plansDir, "plan.yaml"))
+	}
+	if document.DetectFormat(path, []byte(raw)) == document.YAML && !document.Expands(path, []byte(raw)) {
+		return path
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if document.DetectFormat(path, []byte(raw)) == document.YAML {
+		base += "-edited"
+	}
+	return newFile(base + ".yaml")
+}
+
+// newFile returns file, or file with a number before its
+// extension if it exists, so that no file is overwritten
+func newFile(file string) string {
+	ext := filepath.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return file
+		}
+		file = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+}
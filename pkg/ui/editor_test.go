@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+)
+
+func TestValidateDocument(t *testing.T) {
+	work := document.Section{Name: "Work", Duration: time.Minute}
+	group := func(sections ...document.Section) document.Section {
+		return document.Section{Name: "Circuit", Sections: sections}
+	}
+
+	tests := []struct {
+		name     string
+		sections []document.Section
+		err      string
+	}{
+		{"valid", []document.Section{work, group(work, group(work))}, ""},
+		{"anchored", []document.Section{{Name: "Bake", Until: &document.Clock{Hour: 19}}}, ""},
+		{"no section", nil, "the document has no section"},
+		{"no name", []document.Section{work, {Duration: time.Minute}}, "section 2 has no name"},
+		{"no duration", []document.Section{{Name: "Plank"}}, "Plank has no duration"},
+		{"nested without name", []document.Section{work, group(work, document.Section{Duration: time.Minute})}, "section 2.2 has no name"},
+		{"deeply nested without name", []document.Section{group(group(document.Section{Duration: time.Minute}))}, "section 1.1.1 has no name"},
+		{"nested without duration", []document.Section{group(work, document.Section{Name: "Plank"})}, "Plank has no duration"},
+	}
+
+	for _, tt := range tests {
+		err := validateDocument(document.Document{Sections: tt.sections})
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mum4k/termdash/keyboard"
+)
+
+// Action is what a key press triggers
+type Action string
+
+const (
+	ActionPause      Action = "pause"
+	ActionSkip       Action = "skip"
+	ActionNext       Action = "next"
+	ActionPrevious   Action = "previous"
+	ActionRestart    Action = "restart"
+	ActionPlan       Action = "plan"
+	ActionLayout     Action = "layout"
+	ActionStats      Action = "stats"
+	ActionEdit       Action = "edit"
+	ActionVolumeUp   Action = "volume-up"
+	ActionVolumeDown Action = "volume-down"
+	ActionMute       Action = "mute"
+	ActionHelp       Action = "help"
+	ActionQuit       Action = "quit"
+)
+
+// Actions lists every action in the order of the help screen
+var Actions = []Action{
+	ActionPause,
+	ActionSkip,
+	ActionNext,
+	ActionPrevious,
+	ActionRestart,
+	ActionPlan,
+	ActionLayout,
+	ActionStats,
+	ActionEdit,
+	ActionVolumeUp,
+	ActionVolumeDown,
+	ActionMute,
+	ActionHelp,
+	ActionQuit,
+}
+
+var actionDescriptions = map[Action]string{
+	ActionPause:      "pause or resume",
+	ActionSkip:       "skip the get ready countdown",
+	ActionNext:       "go to the next section",
+	ActionPrevious:   "go back to the previous section",
+	ActionRestart:    "play the document from the start",
+	ActionPlan:       "show or hide the plan",
+	ActionLayout:     "switch between the automatic, full, compact and minimal layouts",
+	ActionStats:      "show or hide the training stats",
+	ActionEdit:       "edit the document being played, or a new one",
+	ActionVolumeUp:   "raise the volume",
+	ActionVolumeDown: "lower the volume",
+	ActionMute:       "mute or unmute",
+	ActionHelp:       "show or hide this help",
+	ActionQuit:       "quit",
+}
+
+// Bindings maps actions to the keys triggering them
+type Bindings map[Action][]keyboard.Key
+
+// DefaultBindings returns the bindings used when none is configured
+func DefaultBindings() Bindings {
+	return Bindings{
+		ActionPause:      {keyboard.KeySpace},
+		ActionSkip:       {'s'},
+		ActionNext:       {'n'},
+		ActionPrevious:   {'b'},
+		ActionRestart:    {'r'},
+		ActionPlan:       {'p'},
+		ActionLayout:     {'l'},
+		ActionStats:      {'t'},
+		ActionEdit:       {'e'},
+		ActionVolumeUp:   {'+', '='},
+		ActionVolumeDown: {'-'},
+		ActionMute:       {'m'},
+		ActionHelp:       {'?'},
+		ActionQuit:       {keyboard.KeyEsc, keyboard.KeyCtrlC},
+	}
+}
+
+// KeyNames are the names of the keys bound to an action. They are
+// written as a single name or a list of names in YAML documents.
+type KeyNames []string
+
+// UnmarshalYAML implements yaml.Unmarshaler. Names are read as strings
+// so that keys such as n or y are not read as booleans.
+func (o *KeyNames) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*o = KeyNames{name}
+		return nil
+	}
+
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+	*o = names
+	return nil
+}
+
+// ParseBindings returns the default bindings overridden by raw,
+// which maps action names to key names
+func ParseBindings(raw map[string]KeyNames) (Bindings, error) {
+	b := DefaultBindings()
+	for name, names := range raw {
+		action := Action(name)
+		if _, ok := actionDescriptions[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+
+		var keys []keyboard.Key
+		for _, n := range names {
+			k, err := parseKey(n)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			keys = append(keys, k)
+		}
+		b[action] = keys
+	}
+
+	// A key triggers a single action
+	seen := map[keyboard.Key]Action{}
+	for _, action := range Actions {
+		for _, k := range b[action] {
+			if other, ok := seen[k]; ok {
+				return nil, fmt.Errorf("key %s is bound to both %s and %s", keyName(k), other, action)
+			}
+			seen[k] = action
+		}
+	}
+
+	return b, nil
+}
+
+// namedKeys are the keys that are not written as the character they type
+var namedKeys = map[string]keyboard.Key{
+	"space":     keyboard.KeySpace,
+	"esc":       keyboard.KeyEsc,
+	"enter":     keyboard.KeyEnter,
+	"tab":       keyboard.KeyTab,
+	"backspace": keyboard.KeyBackspace2,
+	"delete":    keyboard.KeyDelete,
+	"insert":    keyboard.KeyInsert,
+	"home":      keyboard.KeyHome,
+	"end":       keyboard.KeyEnd,
+	"pgup":      keyboard.KeyPgUp,
+	"pgdn":      keyboard.KeyPgDn,
+	"up":        keyboard.KeyArrowUp,
+	"down":      keyboard.KeyArrowDown,
+	"left":      keyboard.KeyArrowLeft,
+	"right":     keyboard.KeyArrowRight,
+	"f1":        keyboard.KeyF1,
+	"f2":        keyboard.KeyF2,
+	"f3":        keyboard.KeyF3,
+	"f4":        keyboard.KeyF4,
+	"f5":        keyboard.KeyF5,
+	"f6":        keyboard.KeyF6,
+	"f7":        keyboard.KeyF7,
+	"f8":        keyboard.KeyF8,
+	"f9":        keyboard.KeyF9,
+	"f10":       keyboard.KeyF10,
+	"f11":       keyboard.KeyF11,
+	"f12":       keyboard.KeyF12,
+}
+
+// ctrlKeys are the ctrl+letter keys, from ctrl+a to ctrl+z
+var ctrlKeys = []keyboard.Key{
+	keyboard.KeyCtrlA, keyboard.KeyCtrlB, keyboard.KeyCtrlC, keyboard.KeyCtrlD,
+	keyboard.KeyCtrlE, keyboard.KeyCtrlF, keyboard.KeyCtrlG, keyboard.KeyBackspace,
+	keyboard.KeyTab, keyboard.KeyCtrlJ, keyboard.KeyCtrlK, keyboard.KeyCtrlL,
+	keyboard.KeyEnter, keyboard.KeyCtrlN, keyboard.KeyCtrlO, keyboard.KeyCtrlP,
+	keyboard.KeyCtrlQ, keyboard.KeyCtrlR, keyboard.KeyCtrlS, keyboard.KeyCtrlT,
+	keyboard.KeyCtrlU, keyboard.KeyCtrlV, keyboard.KeyCtrlW, keyboard.KeyCtrlX,
+	keyboard.KeyCtrlY, keyboard.KeyCtrlZ,
+}
+
+// parseKey reads a key name: a single character, a named
+// key such as "space" or "left", or "ctrl+" and a letter
+func parseKey(name string) (keyboard.Key, error) {
+	if r := []rune(name); len(r) == 1 {
+		return keyboard.Key(r[0]), nil
+	}
+
+	lower := strings.ToLower(name)
+	if k, ok := namedKeys[lower]; ok {
+		return k, nil
+	}
+	if l := strings.TrimPrefix(lower, "ctrl+"); l != lower && len(l) == 1 && l[0] >= 'a' && l[0] <= 'z' {
+		return ctrlKeys[l[0]-'a'], nil
+	}
+
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
+// keyName returns the name of k as read by parseKey
+func keyName(k keyboard.Key) string {
+	for name, named := range namedKeys {
+		if named == k {
+			return name
+		}
+	}
+	for i, ctrl := range ctrlKeys {
+		if ctrl == k {
+			return "ctrl+" + string(rune('a'+i))
+		}
+	}
+	return string(k)
+}
+
+// Names returns the names of the keys bound to every action
+func (o Bindings) Names() map[string]KeyNames {
+	names := map[string]KeyNames{}
+	for action, keys := range o {
+		for _, k := range keys {
+			names[string(action)] = append(names[string(action)], keyName(k))
+		}
+	}
+	return names
+}
+
+// help returns the text of the help screen
+func (o Bindings) help() string {
+	var lines []string
+	for _, action := range Actions {
+		var names []string
+		for _, k := range o[action] {
+			names = append(names, keyName(k))
+		}
+		lines = append(lines, fmt.Sprintf("%-12s %-14s %s", action, strings.Join(names, ", "), actionDescriptions[action]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// layout is the arrangement of the widgets of the body
+type layout int
+
+const (
+	// layoutAuto picks one of the other layouts from the terminal size
+	layoutAuto layout = iota
+	// layoutFull displays every widget
+	layoutFull
+	// layoutCompact keeps the section's name and remaining time
+	// above a line summing up the round
+	layoutCompact
+	// layoutMinimal only keeps the summary line
+	layoutMinimal
+)
+
+var layoutNames = map[layout]string{
+	layoutAuto:    "auto",
+	layoutFull:    "full",
+	layoutCompact: "compact",
+	layoutMinimal: "minimal",
+}
+
+func (o layout) String() string {
+	return layoutNames[o]
+}
+
+// next returns the layout following o when the layout key is pressed
+func (o layout) next() layout {
+	return (o + 1) % (layoutMinimal + 1)
+}
+
+// Smallest terminal sizes of the full and compact layouts, under which
+// the segment displays are too small to be drawn
+var (
+	fullSize    = image.Point{100, 30}
+	compactSize = image.Point{40, 15}
+)
+
+// fitLayout returns the largest layout fitting a terminal of the given size
+func fitLayout(size image.Point) layout {
+	switch {
+	case size.X >= fullSize.X && size.Y >= fullSize.Y:
+		return layoutFull
+	case size.X >= compactSize.X && size.Y >= compactSize.Y:
+		return layoutCompact
+	default:
+		return layoutMinimal
+	}
+}
+
+// watchSize sends the size of t on ch every time it changes
+func watchSize(t terminalapi.Terminal, ch chan<- image.Point) {
+	var last image.Point
+	for range time.Tick(redrawInterval) {
+		if size := t.Size(); size != last {
+			last = size
+			ch <- size
+		}
+	}
+}
+
+// summary is what the summary line displays
+type summary struct {
+	name      string
+	color     cell.Color
+	remaining time.Duration
+	total     time.Duration
+	next      string
+	paused    bool
+	// status is the error preventing a document from being played
+	status string
+}
+
+// newSummaryText creates a new Text widget summing the round up on one
+// line: the current section, its remaining time, the total remaining
+// time and the next section
+func newSummaryText(ch chan summary, th theme) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			s := <-ch
+			t.Reset()
+
+			write := func(txt string, color cell.Color) {
+				if err := t.Write(printable(txt), text.WriteCellOpts(cell.FgColor(color))); err != nil {
+					panic(err)
+				}
+			}
+
+			if s.status != "" {
+				write(s.status, th.invalid)
+				continue
+			}
+			if s.name == "" {
+				write("No document, type a file name in the field above", th.text)
+				continue
+			}
+
+			remaining := th.running
+			if s.remaining <= 3*time.Second {
+				remaining = th.ending
+			}
+			write(s.name+" ", s.color)
+			write(s.remaining.String(), remaining)
+			write(fmt.Sprintf(" | total %v", s.total), th.text)
+			if s.next != "" {
+				write(" | next "+s.next, th.text)
+			}
+			if s.paused {
+				write(" | paused", th.status)
+			}
+		}
+	}()
+
+	return t, nil
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"image"
+	"sync"
+
+	"github.com/mum4k/termdash/mouse"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// planText is the plan panel. Clicking a section sends its
+// index in the round on jump.
+type planText struct {
+	*text.Text
+	jump chan<- int
+
+	// first is the index of the section on the first line,
+	// -1 while the sections are not listed
+	first int
+	count int
+
+	// mu protects first and count.
+	mu sync.Mutex
+}
+
+// setLines records the sections listed by the panel, from first to count-1
+func (o *planText) setLines(first, count int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.first = first
+	o.count = count
+}
+
+// Mouse implements widgetapi.Widget.Mouse.
+// Lines are not wrapped, a line is a section.
+func (o *planText) Mouse(m *terminalapi.Mouse) error {
+	if m.Button != mouse.ButtonLeft {
+		return nil
+	}
+
+	o.mu.Lock()
+	i := o.first + m.Position.Y
+	ok := o.first >= 0 && i < o.count
+	o.mu.Unlock()
+
+	if ok {
+		o.jump <- i
+	}
+	return nil
+}
+
+// Options implements widgetapi.Widget.Options.
+func (o *planText) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize: image.Point{1, 1},
+		WantMouse:   widgetapi.MouseScopeWidget,
+	}
+}
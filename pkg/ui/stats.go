@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+const (
+	// statsDays is the number of days of the stats chart
+	statsDays = 14
+	// statsPeriods is the number of weeks and months of the stats
+	statsPeriods = 4
+)
+
+// statsReport returns the stats of the history at the time of the call
+func statsReport() string {
+	sessions, err := history.Read()
+	if err != nil {
+		return fmt.Sprintf("Cannot read the history: %v", err)
+	}
+	if len(sessions) == 0 {
+		return "No session recorded yet"
+	}
+	return history.Compute(sessions, time.Now(), statsDays).Report(statsPeriods)
+}
+
+// newStatsText creates a new Text widget displaying the reports sent on ch
+func newStatsText(ch chan string, th theme) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			report := <-ch
+			t.Reset()
+			if err := t.Write(printable(report), text.WriteCellOpts(cell.FgColor(th.text))); err != nil {
+				panic(err)
+			}
+		}
+	}()
+
+	return t, nil
+}
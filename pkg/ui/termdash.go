@@ -2,22 +2,30 @@ package ui
 
 import (
 	"context"
-	"flag"
+	"fmt"
+	"image"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/Juli3nnicolas/bipper/pkg/bipper"
 	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/Juli3nnicolas/bipper/pkg/history"
+	"github.com/Juli3nnicolas/bipper/pkg/sound"
 	"github.com/Juli3nnicolas/bipper/pkg/syncro"
 	"github.com/mum4k/termdash"
 	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
-	"github.com/mum4k/termdash/keyboard"
 	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/terminal/tcell"
 	"github.com/mum4k/termdash/terminal/termbox"
 	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgets/button"
 	"github.com/mum4k/termdash/widgets/donut"
 	"github.com/mum4k/termdash/widgets/segmentdisplay"
 	"github.com/mum4k/termdash/widgets/text"
@@ -29,37 +37,172 @@ type UI interface {
 }
 
 type TermDashUI struct {
-	bip                  *bipper.Bipper
-	pauser               *Pauser
-	bipFile              string
-	endBipFile           string
-	sectionFile          chan string
-	currentSection       chan string
+	bip      *bipper.Bipper
+	bindings Bindings
+	actions  chan Action
+	// quit stops the UI, it is set by Run
+	quit        context.CancelFunc
+	bipFile     string
+	endBipFile  string
+	terminal    string
+	sectionFile chan string
+	filter      chan string
+	browser     chan browserView
+	plansDir    string
+	themeName   string
+	colors      int
+	theme       theme
+	browsing    bool
+	// typing is true while the file path field is focused
+	typing *syncro.AtomicBool
+	// editing is true while the editor is open
+	editing              *syncro.AtomicBool
+	currentSection       chan document.Section
+	sectionDescription   chan string
+	donutColor           chan cell.Color
 	remainingTime        chan time.Duration
 	percentRemainingTime chan int
 	totalRemaining       chan time.Duration
-	rawDocument          chan string
+	plan                 chan planView
+	jump                 chan int
+	timeline             *Timeline
+	showPlan             bool
+	showHelp             bool
+	showStats            bool
+	stats                chan string
+	layout               layout
+	size                 image.Point // size of the terminal, picks the automatic layout
+	sizes                chan image.Point
+	summary              chan summary
+	container            *container.Container
+	widgets              *widgets
 	isPaused             chan string
+	status               chan string
+	preview              chan preview
+	opened               *openedDocument
+	docOptions           document.Options
+	seed                 int64
+	// seeded is true once SetSeed is called, zero being a valid seed
+	seeded        bool
+	suspendPolicy bipper.SuspendPolicy
+	// checkpointMu orders the checkpoints and the end of the sessions
+	checkpointMu sync.Mutex
 }
 
-func (o *TermDashUI) Init(bipFile, endBipFile string) {
-	o.pauser = NewPauser(keyboard.Key(' '), make(chan bool))
+// planView is what the plan panel displays
+type planView struct {
+	// raw is the document's source, displayed until the round is planned
+	raw      string
+	sections []document.Section
+	// current is the index of the current section, -1 if none
+	current int
+}
+
+// preview is what the next sections panel displays
+type preview struct {
+	upcoming bipper.Upcoming
+	// soon is true when the current section is about to end
+	soon bool
+}
+
+// openedDocument is a document parsed before the UI started
+type openedDocument struct {
+	// path is recorded in the history
+	path string
+	raw  string
+	doc  document.Document
+	// resume is the checkpoint the document is resumed from, if any
+	resume *history.Checkpoint
+}
+
+// Init prepares the UI. terminal is the terminal implementation
+// to use, either "termbox" or "tcell".
+func (o *TermDashUI) Init(bipFile, endBipFile, terminal string) {
+	o.bindings = DefaultBindings()
+	o.actions = make(chan Action)
 	o.bipFile = bipFile
 	o.endBipFile = endBipFile
+	o.terminal = terminal
 	o.sectionFile = make(chan string)
-	o.currentSection = make(chan string)
+	o.filter = make(chan string)
+	o.browser = make(chan browserView)
+	o.plansDir = "."
+	o.themeName = "default"
+	o.colors = 256
+	o.suspendPolicy = bipper.SuspendPause
+	o.currentSection = make(chan document.Section)
+	o.sectionDescription = make(chan string)
+	o.donutColor = make(chan cell.Color)
 	o.remainingTime = make(chan time.Duration)
 	o.percentRemainingTime = make(chan int)
 	o.totalRemaining = make(chan time.Duration)
-	o.rawDocument = make(chan string)
+	o.plan = make(chan planView)
+	o.jump = make(chan int)
 	o.isPaused = make(chan string)
+	o.status = make(chan string)
+	o.preview = make(chan preview)
+	o.sizes = make(chan image.Point)
+	o.summary = make(chan summary)
+	o.stats = make(chan string)
+	o.typing = syncro.NewAtomicBool(false)
+	o.editing = syncro.NewAtomicBool(false)
+}
+
+// Open makes the UI play doc as soon as it runs. raw is the document's
+// source and path where it comes from. Must be called before Run.
+func (o *TermDashUI) Open(path, raw string, doc document.Document) {
+	o.opened = &openedDocument{path: path, raw: raw, doc: doc}
+}
+
+// Resume makes the document passed to Open start
+// from checkpoint c. Must be called after Open.
+func (o *TermDashUI) Resume(c history.Checkpoint) {
+	o.opened.resume = &c
+}
+
+// SetBindings sets the keys triggering the actions
+func (o *TermDashUI) SetBindings(b Bindings) {
+	o.bindings = b
+}
+
+// SetTheme sets the color theme, one of Themes
+func (o *TermDashUI) SetTheme(name string) {
+	o.themeName = name
+}
+
+// SetColors sets the number of colors of the terminal, 8 or 256
+func (o *TermDashUI) SetColors(colors int) {
+	o.colors = colors
+}
+
+// SetDocumentOptions sets the options used to read
+// the documents opened from the UI
+func (o *TermDashUI) SetDocumentOptions(opts document.Options) {
+	o.docOptions = opts
+}
+
+// SetPlansDir sets the directory listed by the file browser
+func (o *TermDashUI) SetPlansDir(dir string) {
+	o.plansDir = dir
+}
+
+// SetSuspendPolicy sets what is done when the computer was suspended during a section
+func (o *TermDashUI) SetSuspendPolicy(p bipper.SuspendPolicy) {
+	o.suspendPolicy = p
+}
+
+// SetSeed makes the random durations and the shuffled sections
+// reproducible. A different seed is picked at each run if it is not called.
+func (o *TermDashUI) SetSeed(seed int64) {
+	o.seed = seed
+	o.seeded = true
 }
 
 const (
 	emptyCurrentSection string        = "-"
+	prestartStr         string        = "GET READY"
 	isPausedStr         string        = "||"
 	notPausedStr        string        = " "
-	emptyRawDocument    string        = " "
 	emptyRemainingTime  time.Duration = time.Duration(0)
 )
 
@@ -69,54 +212,108 @@ const redrawInterval = 250 * time.Millisecond
 // widgets holds the widgets used by this demo.
 type widgets struct {
 	currentSectionMessage *segmentdisplay.SegmentDisplay
-	openedFileMessage     *textinput.TextInput
-	blank                 *text.Text
-	rawDocument           *text.Text
+	sectionDescription    *text.Text
+	openedFileMessage     *fileInput
+	status                *text.Text
+	plan                  *planText
+	browser               *text.Text
+	help                  *text.Text
+	stats                 *text.Text
+	editor                *editor
+	timeline              *Timeline
 	remainingTime         *segmentdisplay.SegmentDisplay
 	percentRemainingTime  *donut.Donut
+	preview               *text.Text
+	summary               *text.Text
 	totalRemaining        *segmentdisplay.SegmentDisplay
-	pause                 *Pauser
+	dispatcher            *Dispatcher
 	isPaused              *segmentdisplay.SegmentDisplay
+	buttons               []*button.Button
 }
 
 // newWidgets creates all widgets used by this demo.
 func (o *TermDashUI) newWidgets(c *container.Container) (*widgets, error) {
-	openedFileMessage, err := newTextInput(o.sectionFile)
+	o.theme = newTheme(o.themeName, o.colors)
+	o.timeline = newTimeline(o.theme)
+
+	openedFileMessage, err := newTextInput(o.sectionFile, o.filter, o.typing, o.theme)
+	if err != nil {
+		return nil, err
+	}
+
+	currentSectionMessage, err := newSectionDisplay(document.Section{Name: emptyCurrentSection}, o.currentSection, o.theme)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionDescription, err := newRollText(o.sectionDescription, o.theme)
+	if err != nil {
+		return nil, err
+	}
+
+	isPaused, err := newSegmentDisplay(notPausedStr, o.isPaused, o.theme.display)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := newStatusText(o.status, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	currentSectionMessage, err := newSegmentDisplay(emptyCurrentSection, o.currentSection)
+	plan, err := newPlanText(o.plan, o.jump, o.theme)
+	if err != nil {
+		return nil, err
+	}
+	browser, err := newBrowserText(o.browser, o.docOptions, o.theme)
+	if err != nil {
+		return nil, err
+	}
+	help, err := newHelpText(o.bindings)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := newStatsText(o.stats, o.theme)
+	if err != nil {
+		return nil, err
+	}
+	editor, err := newEditor(o.actions, o.status, o.editing, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	isPaused, err := newSegmentDisplay(notPausedStr, o.isPaused)
+	// Key shortcuts are ignored while typing in the file path field
+	dispatcher := NewDispatcher(o.bindings, o.actions)
+	dispatcher.MuteWhile(o.typing.Value)
+	// The editor handles the keyboard while it is open
+	dispatcher.IgnoreWhile(o.editing.Value)
+
+	remainingTime, err := newTimeSegmentDisplay(emptyRemainingTime.String(), o.remainingTime, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	blank, err := newTextLabel(" ")
+	percentRemainingTime, err := newPercentDonut(o.percentRemainingTime, o.donutColor, o.theme.donut)
 	if err != nil {
 		return nil, err
 	}
 
-	rawDocument, err := newRollText(o.rawDocument)
+	preview, err := newPreviewText(o.preview, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	remainingTime, err := newTimeSegmentDisplay(emptyRemainingTime.String(), o.remainingTime)
+	summary, err := newSummaryText(o.summary, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	percentRemainingTime, err := newPercentDonut(o.percentRemainingTime, cell.ColorGreen)
+	buttons, err := newButtons(o.actions, o.theme)
 	if err != nil {
 		return nil, err
 	}
 
-	totalRemaining, err := newTimeSegmentDisplay(emptyRemainingTime.String(), o.totalRemaining)
+	totalRemaining, err := newTimeSegmentDisplay(emptyRemainingTime.String(), o.totalRemaining, o.theme)
 	if err != nil {
 		return nil, err
 	}
@@ -124,55 +321,76 @@ func (o *TermDashUI) newWidgets(c *container.Container) (*widgets, error) {
 	return &widgets{
 		openedFileMessage:     openedFileMessage,
 		currentSectionMessage: currentSectionMessage,
-		blank:                 blank,
-		rawDocument:           rawDocument,
+		sectionDescription:    sectionDescription,
+		status:                status,
+		plan:                  plan,
+		browser:               browser,
+		help:                  help,
+		stats:                 stats,
+		editor:                editor,
+		timeline:              o.timeline,
 		remainingTime:         remainingTime,
 		percentRemainingTime:  percentRemainingTime,
+		preview:               preview,
+		summary:               summary,
 		totalRemaining:        totalRemaining,
-		pause:                 o.pauser,
+		dispatcher:            dispatcher,
 		isPaused:              isPaused,
+		buttons:               buttons,
 	}, nil
 }
 
-// gridLayout prepares container options that represent the desired screen layout.
-// This function demonstrates the use of the grid builder.
-// gridLayout() and contLayout() demonstrate the two available layout APIs and
-// both produce equivalent layouts for layoutType layoutAll.
-func gridLayout(w *widgets) ([]container.Option, error) {
-
-	builder := grid.New()
-	builder.Add(
-		grid.RowHeightPerc(5,
-			grid.ColWidthPerc(98,
-				grid.Widget(w.openedFileMessage,
-					container.Border(linestyle.None)),
-			),
-			grid.ColWidthPerc(1,
-				grid.Widget(w.pause),
+// rootLayout prepares the container options of the screen: the file
+// path field on the first line and the body below it. The body is laid
+// out by bodyLayout so that updating it keeps the focus of the file
+// path field. The hidden dispatcher takes the first cell.
+func rootLayout(w *widgets) []container.Option {
+	return []container.Option{
+		container.SplitHorizontal(
+			container.Top(
+				container.SplitVertical(
+					container.Left(container.PlaceWidget(w.dispatcher)),
+					container.Right(container.PlaceWidget(w.openedFileMessage)),
+					container.SplitFixed(1),
+				),
 			),
+			container.Bottom(container.ID(bodyID)),
+			container.SplitFixed(1),
 		),
-		grid.RowHeightPerc(25, grid.Widget(w.currentSectionMessage,
+	}
+}
+
+// bodyLayout prepares the container options of the body, l must not be
+// layoutAuto. side is the panel displayed on the left of the screen,
+// the minimal layout only displays the help, the stats and the editor.
+func bodyLayout(w *widgets, l layout, side panel) ([]container.Option, error) {
+	full := []grid.Element{
+		grid.RowHeightPerc(20, grid.Widget(w.currentSectionMessage,
 			container.Border(linestyle.None),
 		)),
-		grid.RowHeightPerc(5, grid.Widget(w.blank,
+		grid.RowHeightPerc(5, grid.Widget(w.sectionDescription,
 			container.Border(linestyle.None),
 		)),
-		grid.RowHeightPerc(55,
-			/*grid.ColWidthPerc(20,
-				grid.Widget(w.rawDocument,
-					container.Border(linestyle.None),
-				),
-			),*/
-			grid.ColWidthPerc(50,
+		grid.RowHeightPerc(5, grid.Widget(w.status,
+			container.Border(linestyle.None),
+		)),
+		grid.RowHeightPerc(40,
+			grid.ColWidthPerc(40,
 				grid.Widget(w.remainingTime,
 					container.Border(linestyle.None),
 				),
 			),
-			grid.ColWidthPerc(50,
+			grid.ColWidthPerc(35,
 				grid.Widget(w.percentRemainingTime,
 					container.Border(linestyle.None),
 				),
 			),
+			grid.ColWidthPerc(25,
+				grid.Widget(w.preview,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Next"),
+				),
+			),
 		),
 		grid.RowHeightPerc(10,
 			grid.ColWidthPerc(90,
@@ -186,7 +404,90 @@ func gridLayout(w *widgets) ([]container.Option, error) {
 				),
 			),
 		),
-	)
+		grid.RowHeightPerc(5, grid.Widget(w.timeline,
+			container.Border(linestyle.None),
+		)),
+		grid.RowHeightPerc(10, buttonColumns(w.buttons)...),
+	}
+
+	// The segment displays need 5 rows, the other widgets of the compact
+	// layout are given a single line. The body is split by percentage
+	// in every layout as container.Update keeps the previous split.
+	compact := []grid.Element{
+		grid.RowHeightPerc(40, grid.Widget(w.currentSectionMessage)),
+		grid.RowHeightPerc(59,
+			grid.RowHeightFixed(1, grid.Widget(w.summary)),
+			grid.RowHeightFixed(1, grid.Widget(w.timeline)),
+			grid.RowHeightFixed(1, grid.Widget(w.status)),
+			grid.RowHeightPerc(99, grid.Widget(w.remainingTime)),
+		),
+	}
+
+	main, panelWidth := full, 25
+	switch l {
+	case layoutCompact:
+		main, panelWidth = compact, 35
+	case layoutMinimal:
+		main = []grid.Element{grid.Widget(w.summary)}
+		if side != helpPanel && side != statsPanel && side != editorPanel {
+			side = noPanel
+		}
+	}
+
+	// The main rows fill the screen unless a side panel is displayed
+	columns := []grid.Element{grid.ColWidthPerc(99, main...)}
+	switch side {
+	case planPanel:
+		columns = []grid.Element{
+			grid.ColWidthPerc(panelWidth,
+				grid.Widget(w.plan,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Plan"),
+				),
+			),
+			grid.ColWidthPerc(99-panelWidth, main...),
+		}
+	case browserPanel:
+		columns = []grid.Element{
+			grid.ColWidthPerc(panelWidth,
+				grid.Widget(w.browser,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Documents"),
+				),
+			),
+			grid.ColWidthPerc(99-panelWidth, main...),
+		}
+	case helpPanel:
+		columns = []grid.Element{
+			grid.ColWidthPerc(99,
+				grid.Widget(w.help,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Keys (press the help key to close)"),
+				),
+			),
+		}
+	case statsPanel:
+		columns = []grid.Element{
+			grid.ColWidthPerc(99,
+				grid.Widget(w.stats,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Stats (press the stats key to close)"),
+				),
+			),
+		}
+	case editorPanel:
+		columns = []grid.Element{
+			grid.ColWidthPerc(99,
+				grid.Widget(w.editor,
+					container.Border(linestyle.Light),
+					container.BorderTitle("Editor"),
+				),
+			),
+		}
+	}
+
+	builder := grid.New()
+	builder.Add(columns...)
 
 	gridOpts, err := builder.Build()
 	if err != nil {
@@ -195,9 +496,57 @@ func gridLayout(w *widgets) ([]container.Option, error) {
 	return gridOpts, nil
 }
 
+// panel is the panel displayed in the body. The side panels are
+// displayed on the left of the screen, the help, the stats and the editor fill the body.
+type panel int
+
+const (
+	noPanel panel = iota
+	planPanel
+	browserPanel
+	helpPanel
+	statsPanel
+	editorPanel
+)
+
+// updateLayout lays the body out according to the UI's state.
+// The editor takes precedence over the help, the help over the stats,
+// the stats over the file browser and the file browser over the plan.
+func (o *TermDashUI) updateLayout() {
+	side := noPanel
+	if o.editing.Value() {
+		side = editorPanel
+	} else if o.showHelp {
+		side = helpPanel
+	} else if o.showStats {
+		side = statsPanel
+	} else if o.browsing {
+		side = browserPanel
+	} else if o.showPlan {
+		side = planPanel
+	}
+
+	l := o.layout
+	if l == layoutAuto {
+		l = fitLayout(o.size)
+	}
+
+	gridOpts, err := bodyLayout(o.widgets, l, side)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := o.container.Update(bodyID, gridOpts...); err != nil {
+		panic(err)
+	}
+}
+
 // rootID is the ID assigned to the root container.
 const rootID = "root"
 
+// bodyID is the ID assigned to the container below the file path field.
+const bodyID = "body"
+
 // Terminal implementations
 const (
 	termboxTerminal = "termbox"
@@ -205,18 +554,18 @@ const (
 )
 
 func (o *TermDashUI) Run() {
-	terminalPtr := flag.String("terminal",
-		"termbox",
-		"The terminal implementation to use. Available implementations are 'termbox' and 'tcell' (default = termbox).")
-	flag.Parse()
+	mode := terminalapi.ColorMode256
+	if o.colors == 8 {
+		mode = terminalapi.ColorModeNormal
+	}
 
 	var t terminalapi.Terminal
 	var err error
-	switch terminal := *terminalPtr; terminal {
+	switch terminal := o.terminal; terminal {
 	case termboxTerminal:
-		t, err = termbox.New(termbox.ColorMode(terminalapi.ColorMode256))
+		t, err = termbox.New(termbox.ColorMode(mode))
 	case tcellTerminal:
-		t, err = tcell.New(tcell.ColorMode(terminalapi.ColorMode256))
+		t, err = tcell.New(tcell.ColorMode(mode))
 	default:
 		log.Fatalf("Unknown terminal implementation '%s' specified. Please choose between 'termbox' and 'tcell'.", terminal)
 		return
@@ -238,27 +587,83 @@ func (o *TermDashUI) Run() {
 		panic(err)
 	}
 
-	gridOpts, err := gridLayout(w) // equivalent to contLayout(w)
-	if err != nil {
+	if err := c.Update(rootID, rootLayout(w)...); err != nil {
 		panic(err)
 	}
 
-	if err := c.Update(rootID, gridOpts...); err != nil {
+	o.container = c
+	o.widgets = w
+	o.size = t.Size()
+	go watchSize(t, o.sizes)
+	// The file browser is displayed until a document is played
+	o.browsing = o.opened == nil
+	o.updateLayout()
+
+	o.quit = cancel
+
+	// Poll UI messages
+	go o.pollInput()
+
+	if err := termdash.Run(ctx, t, c, termdash.RedrawInterval(redrawInterval)); err != nil {
 		panic(err)
 	}
 
-	quitter := func(k *terminalapi.Keyboard) {
-		if k.Key == keyboard.KeyEsc || k.Key == keyboard.KeyCtrlC {
-			cancel()
+	if o.bip != nil {
+		if err := o.saveCheckpoint(o.bip); err != nil {
+			log.Println(err)
+		}
+		if err := o.record(o.bip, false); err != nil {
+			log.Println(err)
 		}
 	}
+}
 
-	// Poll UI messages
-	go o.pollInput()
+// record appends the session of bip to the history unless
+// it is already recorded or no section was played
+func (o *TermDashUI) record(bip *bipper.Bipper, completed bool) error {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
 
-	if err := termdash.Run(ctx, t, c, termdash.KeyboardSubscriber(quitter), termdash.RedrawInterval(redrawInterval)); err != nil {
-		panic(err)
+	s, ok := bip.Finish(completed)
+	if !ok || len(s.Sections) == 0 {
+		return nil
 	}
+
+	// A completed session cannot be resumed
+	if completed {
+		if err := history.RemoveCheckpoint(); err != nil {
+			return fmt.Errorf("checkpoint: %v", err)
+		}
+	}
+
+	if err := history.Append(s); err != nil {
+		return fmt.Errorf("history: %v", err)
+	}
+	return nil
+}
+
+// reloadPeriod is the time between two checks of
+// the modification time of the document being played
+const reloadPeriod = time.Second
+
+// checkpointPeriod is the time between two checkpoints of the session
+const checkpointPeriod = 5 * time.Second
+
+// saveCheckpoint saves where the session of bip stands,
+// nothing is saved once the session is finished
+func (o *TermDashUI) saveCheckpoint(bip *bipper.Bipper) error {
+	o.checkpointMu.Lock()
+	defer o.checkpointMu.Unlock()
+
+	c, ok := bip.Checkpoint()
+	if !ok {
+		return nil
+	}
+	c.Vars = o.docOptions.Vars
+	if err := history.SaveCheckpoint(c); err != nil {
+		return fmt.Errorf("checkpoint: %v", err)
+	}
+	return nil
 }
 
 func (o *TermDashUI) pollInput() {
@@ -269,124 +674,444 @@ func (o *TermDashUI) pollInput() {
 	// Is true if the countdown can be paused
 	canPause := syncro.NewAtomicBool(false)
 	isPaused := false
+	// Is true during the "get ready" countdown
+	prestarting := false
+	// What follows the current section
+	var next preview
+	// The sections of the current round
+	var view planView
+	// The text typed in the file path field
+	var filter string
+	// The document being played, restarted on demand
+	var playing openedDocument
+	// The summary line, sent when it changes
+	var line, shown summary
+	checkpoints := time.Tick(checkpointPeriod)
+	// The file of the document being played, reloaded when it is modified
+	var watched string
+	var modified time.Time
+	reloads := time.Tick(reloadPeriod)
+
+	// browse displays the file browser when no document
+	// is played or when a file name is being typed
+	browse := func() {
+		if browsing := o.bip == nil || filter != ""; browsing != o.browsing {
+			o.browsing = browsing
+			o.updateLayout()
+		}
+	}
+
+	// load replaces the running bipper by a new one playing doc
+	load := func(path, raw string, doc document.Document, resume *history.Checkpoint, err error) {
+		currentSectionRemainingTime = emptyFloatDuration
+		currentSectionMaxDuration = emptyFloatDuration
+		isPaused = false
+		prestarting = false
+		line = summary{}
+
+		if o.bip != nil {
+			canPause.False()
+			if err := o.record(o.bip, false); err != nil {
+				o.status <- err.Error()
+			}
+			// The bipper is closed once it stops
+			o.bip.Stop()
+		}
+
+		if err != nil {
+			// Nothing is played, the editor starts a new document
+			o.bip = nil
+			playing = openedDocument{}
+			watched = ""
+			o.showSection(document.Section{Name: emptyCurrentSection})
+			o.plan <- planView{}
+			o.timeline.SetPlan(nil)
+			o.remainingTime <- emptyRemainingTime
+			o.totalRemaining <- emptyRemainingTime
+			o.percentRemainingTime <- 0
+			o.preview <- preview{}
+			o.status <- err.Error()
+			line.status = err.Error()
+			browse()
+			return
+		}
+
+		playing = openedDocument{path: path, raw: raw, doc: doc}
+		watched = ""
+		if info, err := os.Stat(path); err == nil && filepath.IsAbs(path) {
+			watched, modified = path, info.ModTime()
+		}
+		o.bip = &bipper.Bipper{}
+		o.bip.InitDocument(o.bipFile, o.endBipFile, raw, doc)
+		if o.seeded {
+			o.bip.Seed(o.seed)
+		}
+		o.bip.SetSuspendPolicy(o.suspendPolicy)
+		canPause.True()
+
+		o.bip.SetPath(path)
+		if resume != nil {
+			o.bip.Resume(*resume)
+			o.status <- fmt.Sprintf("Resumed at section %d of round %d", resume.Index+1, resume.Round)
+		}
+
+		go func(bip *bipper.Bipper) {
+			bip.Bip()
+			if err := o.record(bip, true); err != nil {
+				o.status <- err.Error()
+			}
+			bip.Close()
+		}(o.bip)
+		browse()
+	}
+
+	if o.opened != nil {
+		load(o.opened.path, o.opened.raw, o.opened.doc, o.opened.resume, nil)
+	}
+	o.browser <- browserView{dir: o.plansDir}
+	o.summary <- line
+	shown = line
 
 	for {
 		// This step is necessary in case no bipper has been set
-		var rawDocument, msg chan string
+		var rawDocument, msg, warning chan string
+		var plan chan []document.Section
 		var currentSection chan document.Section
-		var remainingTime, totalRemaining chan time.Duration
+		var remainingTime, totalRemaining, prestart chan time.Duration
+		var upcoming chan bipper.Upcoming
+		var paused chan bool
 		if o.bip != nil {
+			paused = o.bip.Output.Paused
+			upcoming = o.bip.Output.Upcoming
 			currentSection = o.bip.Output.Section
 			rawDocument = o.bip.Output.RawDoc
+			plan = o.bip.Output.Plan
 			msg = o.bip.Output.Msg
+			warning = o.bip.Output.Warning
 			remainingTime = o.bip.Output.Remaining
 			totalRemaining = o.bip.Output.TotalRemaining
+			prestart = o.bip.Output.Prestart
 		}
 
 		select {
 		// Create a new bipper
 		case file := <-o.sectionFile:
-			currentSectionRemainingTime = emptyFloatDuration
-			currentSectionMaxDuration = emptyFloatDuration
-			isPaused = false
+			path := resolveDocument(o.plansDir, file, o.docOptions)
+			raw, doc, err := document.ReadWithOptions(path, o.docOptions)
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			load(path, raw, doc, nil, err)
+		case filter = <-o.filter:
+			o.browser <- browserView{dir: o.plansDir, filter: filter}
+			browse()
+
+		case action := <-o.actions:
+			switch action {
+			case ActionPause:
+				// A session that is over cannot be paused
+				if o.bip != nil && !o.bip.Ended() && canPause.Value() && send(o.bip.Input.TogglePause) {
+					isPaused = !isPaused
+					line.paused = isPaused
+					if isPaused == true {
+						o.isPaused <- isPausedStr
+					} else {
+						o.isPaused <- notPausedStr
+					}
+				}
+			case ActionSkip:
+				if o.bip != nil && prestarting {
+					send(o.bip.Input.SkipPrestart)
+				}
+			case ActionNext:
+				if o.bip != nil {
+					send(o.bip.Input.Next)
+				}
+			case ActionPrevious:
+				if o.bip != nil {
+					send(o.bip.Input.Previous)
+				}
+			case ActionRestart:
+				if o.bip != nil {
+					load(playing.path, playing.raw, playing.doc, nil, nil)
+					o.isPaused <- notPausedStr
+				}
+			case ActionPlan:
+				o.showPlan = !o.showPlan
+				o.updateLayout()
+			case ActionLayout:
+				o.layout = o.layout.next()
+				o.updateLayout()
+				if o.layout == layoutAuto {
+					o.status <- fmt.Sprintf("Layout %v (%v)", o.layout, fitLayout(o.size))
+				} else {
+					o.status <- fmt.Sprintf("Layout %v", o.layout)
+				}
+			case ActionStats:
+				// The report is read again each time the stats are shown
+				o.showStats = !o.showStats
+				if o.showStats {
+					o.stats <- statsReport()
+				}
+				o.updateLayout()
+			case ActionEdit:
+				// The editor sends the action to be closed
+				if o.editing.Value() {
+					o.editing.False()
+					o.updateLayout()
+					break
+				}
+				doc := document.Document{}
+				if o.bip != nil {
+					var err error
+					if doc, err = sourceDocument(playing.path, playing.raw); err != nil {
+						o.status <- fmt.Sprintf("Cannot edit %s: %v", playing.path, err)
+						break
+					}
+				}
+				target := editTarget(playing.path, playing.raw, o.plansDir)
+				o.widgets.editor.edit(doc, headerComments(playing.raw), target)
+				o.updateLayout()
+				if o.bip != nil && target != playing.path {
+					o.status <- fmt.Sprintf("The edited document will be saved to %s", target)
+				}
+			case ActionVolumeUp:
+				o.status <- fmt.Sprintf("Volume %d/%d", sound.VolumeUp(), sound.MaxVolume)
+			case ActionVolumeDown:
+				o.status <- fmt.Sprintf("Volume %d/%d", sound.VolumeDown(), sound.MaxVolume)
+			case ActionMute:
+				if sound.ToggleMute() {
+					o.status <- "Sound muted"
+				} else {
+					o.status <- "Sound on"
+				}
+			case ActionHelp:
+				o.showHelp = !o.showHelp
+				o.updateLayout()
+			case ActionQuit:
+				o.quit()
+			}
 
+		// Pass the messages to the UI
+
+		// A section of the plan was clicked
+		case i := <-o.jump:
 			if o.bip != nil {
-				canPause.False()
-				o.bip.Close()
+				select {
+				case o.bip.Input.Jump <- i:
+				default:
+				}
 			}
-			o.bip = &bipper.Bipper{}
 
-			err := o.bip.Init(o.bipFile, o.endBipFile, file)
+		// The document is saved in another window
+		case <-reloads:
+			if o.bip == nil || watched == "" {
+				break
+			}
+			info, err := os.Stat(watched)
+			if err != nil || info.ModTime().Equal(modified) {
+				break
+			}
+			modified = info.ModTime()
+
+			// A document that cannot be played, such as a file being
+			// written, is reported and the timer goes on. The summary
+			// keeps the timer, the error is only written to the status.
+			raw, doc, err := document.ReadWithOptions(watched, o.docOptions)
+			if err == nil {
+				err = validateDocument(doc)
+			}
 			if err != nil {
-				o.bip = nil
-				o.currentSection <- emptyCurrentSection
-				o.rawDocument <- emptyRawDocument
-				o.remainingTime <- emptyRemainingTime
-				o.totalRemaining <- emptyRemainingTime
-				o.percentRemainingTime <- 0
+				o.status <- fmt.Sprintf("Cannot reload %s: %v", filepath.Base(watched), err)
+				break
+			}
+			if raw == playing.raw {
 				break
 			}
-			canPause.True()
-
-			go func() {
-				o.bip.Bip()
-				o.bip.Close()
-			}()
 
-		// Pass the messages to the UI
-		case <-o.pauser.PauseKeyDown():
-			if o.bip != nil && canPause.Value() {
-				isPaused = !isPaused
-				o.bip.Input.TogglePause <- isPaused
+			playing = openedDocument{path: watched, raw: raw, doc: doc}
+			select {
+			case <-o.bip.Input.Reload:
+			default:
+			}
+			o.bip.Input.Reload <- bipper.Reload{Raw: raw, Doc: doc}
+			o.status <- fmt.Sprintf("Reloaded %s", filepath.Base(watched))
 
-				if isPaused == true {
-					o.isPaused <- isPausedStr
-				} else {
-					o.isPaused <- notPausedStr
+		case <-checkpoints:
+			if o.bip != nil {
+				if err := o.saveCheckpoint(o.bip); err != nil {
+					o.status <- err.Error()
 				}
 			}
 
+		case size := <-o.sizes:
+			auto := fitLayout(o.size)
+			o.size = size
+			if o.layout == layoutAuto && fitLayout(size) != auto {
+				o.updateLayout()
+			}
+
 		case tmp := <-currentSection:
-			o.currentSection <- tmp.Name
+			o.showSection(tmp)
+			line.name = tmp.Name
+			line.color, _ = o.theme.sectionColors(tmp)
 			currentSectionMaxDuration = tmp.Duration.Seconds()
+			// Sections may be skipped before their countdown is over
+			currentSectionRemainingTime = currentSectionMaxDuration
+		// The raw document is displayed until the first round is planned
 		case tmp := <-rawDocument:
-			o.rawDocument <- tmp
+			view = planView{raw: tmp, current: -1}
+			o.plan <- view
+		case tmp := <-plan:
+			view = planView{sections: tmp, current: -1}
+			o.plan <- view
+			o.timeline.SetPlan(tmp)
+		case tmp := <-upcoming:
+			next = preview{upcoming: tmp}
+			o.preview <- next
+			view.current = tmp.Index - 1
+			o.plan <- view
+			line.next = ""
+			if len(tmp.Sections) > 0 {
+				line.next = tmp.Sections[0].Name
+			}
 		case tmp := <-remainingTime:
 			o.remainingTime <- tmp
 			currentSectionRemainingTime = tmp.Seconds()
+			line.remaining = tmp
+
+			// Highlight the next section during the last 3 seconds
+			if soon := tmp > 0 && tmp <= 3*time.Second; soon != next.soon {
+				next.soon = soon
+				o.preview <- next
+			}
 		case remaining := <-totalRemaining:
 			o.totalRemaining <- remaining
+			o.timeline.SetRemaining(remaining)
+			line.total = remaining
 
 			// Do not accept pauses for the last 3 seconds
 			if remaining <= 3*time.Second {
 				canPause.False()
+			} else {
+				canPause.True()
+			}
+		case tmp := <-prestart:
+			prestarting = tmp > 0
+			if prestarting {
+				if currentSectionMaxDuration == emptyFloatDuration {
+					s := document.Section{Name: prestartStr, Kind: document.KindPrep}
+					o.showSection(s)
+					currentSectionMaxDuration = tmp.Seconds()
+					line.name = s.Name
+					line.color, _ = o.theme.sectionColors(s)
+				}
+				o.remainingTime <- tmp
+				currentSectionRemainingTime = tmp.Seconds()
+				line.remaining = tmp
 			}
+
+		// The bipper paused by itself on waking up, it cannot send it
+		// once the session is over. The pause may come in the last
+		// seconds, when pausing is refused, it must be possible to resume.
+		case <-paused:
+			canPause.True()
+			isPaused = true
+			line.paused = true
+			o.isPaused <- isPausedStr
+
+		case tmp := <-warning:
+			o.status <- tmp
 		case <-msg:
 		}
 
-		if currentSectionMaxDuration != emptyFloatDuration &&
+		// A section that cannot last has no percentage
+		if currentSectionMaxDuration > 0 &&
 			currentSectionRemainingTime != emptyFloatDuration {
 			o.percentRemainingTime <- int((currentSectionRemainingTime / currentSectionMaxDuration) * 100)
 		}
+		if line != shown {
+			o.summary <- line
+			shown = line
+		}
+	}
+}
+
+// send sends a value on an input channel of the bipper without blocking,
+// false is returned if the previous value was not read yet
+func send(input chan bool) bool {
+	select {
+	case input <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+// newHelpText creates a new Text widget listing the key bindings
+func newHelpText(b Bindings) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Write(b.help()); err != nil {
+		return nil, err
 	}
+
+	return t, nil
+}
+
+// showSection displays the name and the description of
+// section s with the colors of its kind
+func (o *TermDashUI) showSection(s document.Section) {
+	_, donutColor := o.theme.sectionColors(s)
+	o.currentSection <- s
+	o.sectionDescription <- s.Description
+	o.donutColor <- donutColor
 }
 
 // newPercentDonut creates a new donut displaying  its current value in percent.
-// The color parameter is used to set its color.
-func newPercentDonut(percentChan chan int, color cell.Color) (*donut.Donut, error) {
+// The color parameter is used to set its initial color, it is changed by
+// sending a new color over colorChan.
+func newPercentDonut(percentChan chan int, colorChan chan cell.Color, color cell.Color) (*donut.Donut, error) {
 	d, err := donut.New(
 		donut.CellOpts(cell.FgColor(color)),
 	)
 	if err != nil {
 		panic(err)
 	}
-	go playDonut(d, percentChan)
+	go playDonut(d, percentChan, colorChan, color)
 
 	return d, err
 }
 
 // playDonut continuously changes the displayed percent value on the donut by the
 // step once every delay. Exits when the context expires.
-func playDonut(d *donut.Donut, percentChan chan int) {
+func playDonut(d *donut.Donut, percentChan chan int, colorChan chan cell.Color, color cell.Color) {
+	percent := 0
 	for {
 		select {
-		case percent := <-percentChan:
-			if err := d.Percent(percent); err != nil {
-				panic(err)
-			}
+		case percent = <-percentChan:
+		case color = <-colorChan:
+		}
+
+		if err := d.Percent(percent, donut.CellOpts(cell.FgColor(color))); err != nil {
+			panic(err)
 		}
 	}
 }
 
-// newTextInput creates a new TextInput field that changes the text on the
-// SegmentDisplay.
-func newTextInput(updateText chan<- string) (*textinput.TextInput, error) {
+// newTextInput creates a new TextInput field sending the submitted
+// file path on updateText. Its text is sent on filter as it is typed,
+// focused is true while the field is focused.
+func newTextInput(updateText chan<- string, filter chan<- string, focused *syncro.AtomicBool, th theme) (*fileInput, error) {
 	input, err := textinput.New(
-		textinput.Label("File path: ", cell.FgColor(cell.ColorWhite)),
-		textinput.MaxWidthCells(20),
-		textinput.PlaceHolder("click here"),
-		textinput.PlaceHolderColor(cell.ColorWhite),
-		textinput.FillColor(cell.ColorNumber(0)),
+		textinput.Label("File path: ", cell.FgColor(th.text)),
+		textinput.PlaceHolder("click here and type to filter the documents"),
+		textinput.PlaceHolderColor(th.text),
+		textinput.FillColor(th.input),
+		textinput.ClearOnSubmit(),
 		textinput.OnSubmit(func(text string) error {
 			updateText <- text
 			return nil
@@ -395,16 +1120,43 @@ func newTextInput(updateText chan<- string) (*textinput.TextInput, error) {
 	if err != nil {
 		return nil, err
 	}
-	return input, err
+
+	// TextInput has no change callback, its text is polled instead
+	go func() {
+		var last string
+		for range time.Tick(redrawInterval / 2) {
+			if text := input.Read(); text != last {
+				last = text
+				filter <- text
+			}
+		}
+	}()
+
+	return &fileInput{TextInput: input, focused: focused}, err
 }
 
-func newTextLabel(msg string) (*text.Text, error) {
-	txt, err := text.New()
-	txt.Write(msg, text.WriteCellOpts(cell.FgColor(cell.ColorWhite)))
+// newStatusText creates a new Text widget that appends every
+// message sent over the channel, the latest message stays visible.
+func newStatusText(ch chan string, th theme) (*text.Text, error) {
+	t, err := text.New(text.RollContent())
 	if err != nil {
 		return nil, err
 	}
-	return txt, err
+
+	go func() {
+		// Messages are separated by a new line written before the next
+		// one, a trailing new line would roll the latest message away
+		separator := ""
+		for {
+			txt := <-ch
+			if err := t.Write(separator+printable(txt), text.WriteCellOpts(cell.FgColor(th.status))); err != nil {
+				panic(err)
+			}
+			separator = "\n"
+		}
+	}()
+
+	return t, nil
 }
 
 func updateChunks(sd *segmentdisplay.SegmentDisplay, text string, color cell.Color) {
@@ -419,8 +1171,8 @@ func updateChunks(sd *segmentdisplay.SegmentDisplay, text string, color cell.Col
 }
 
 // newSegmentDisplay creates a new SegmentDisplay that initially shows the
-// Termdash name. Shows any text that is sent over the channel.
-func newSegmentDisplay(initMsg string, textChan chan string) (*segmentdisplay.SegmentDisplay, error) {
+// Termdash name. Shows any text that is sent over the channel in color.
+func newSegmentDisplay(initMsg string, textChan chan string, color cell.Color) (*segmentdisplay.SegmentDisplay, error) {
 	sd, err := segmentdisplay.New()
 	if err != nil {
 		return nil, err
@@ -438,21 +1190,92 @@ func newSegmentDisplay(initMsg string, textChan chan string) (*segmentdisplay.Se
 	}*/
 
 	text := initMsg
-	updateChunks(sd, text, cell.ColorNumber(200))
+	updateChunks(sd, text, color)
 
 	go func(ch chan string) {
 		for {
 			newTxt := <-ch
-			updateChunks(sd, newTxt, cell.ColorNumber(200))
+			updateChunks(sd, newTxt, color)
 		}
 	}(textChan)
 
 	return sd, nil
 }
 
+// newSectionDisplay creates a new SegmentDisplay showing the name
+// of every section sent over the channel in the color of its kind.
+func newSectionDisplay(init document.Section, ch chan document.Section, th theme) (*segmentdisplay.SegmentDisplay, error) {
+	sd, err := segmentdisplay.New()
+	if err != nil {
+		return nil, err
+	}
+
+	color, _ := th.sectionColors(init)
+	updateChunks(sd, init.Name, color)
+
+	go func() {
+		for {
+			s := <-ch
+			color, _ := th.sectionColors(s)
+			updateChunks(sd, s.Name, color)
+		}
+	}()
+
+	return sd, nil
+}
+
+// newPreviewText creates a new Text widget displaying the
+// round counter and the sections following the current one. The next
+// section is written in capitals during the last seconds so that
+// the change does not rely on colors only.
+func newPreviewText(ch chan preview, th theme) (*text.Text, error) {
+	t, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			p := <-ch
+			t.Reset()
+
+			u := p.upcoming
+			if u.Count == 0 {
+				continue
+			}
+
+			write := func(txt string, color cell.Color) {
+				if err := t.Write(printable(txt), text.WriteCellOpts(cell.FgColor(color))); err != nil {
+					panic(err)
+				}
+			}
+
+			write(fmt.Sprintf("Round %d - %d/%d\n\n", u.Round, u.Index, u.Count), th.text)
+			if len(u.Sections) == 0 {
+				write("Last section\n", th.text)
+			}
+			for i, s := range u.Sections {
+				color, _ := th.sectionColors(s)
+				label := "Then "
+				if i == 0 {
+					label = "Next "
+					if p.soon {
+						label = "NEXT "
+						color = th.ending
+					}
+				}
+				write(label, th.text)
+				write(fmt.Sprintf("%s %v\n", s.Name, s.Duration), color)
+			}
+		}
+	}()
+
+	return t, nil
+}
+
 // newTimeSegmentDisplay creates a new SegmentDisplay that initially shows the
 // Termdash name. Shows any text that is sent over the channel.
-func newTimeSegmentDisplay(initMsg string, timeChan chan time.Duration) (*segmentdisplay.SegmentDisplay, error) {
+func newTimeSegmentDisplay(initMsg string, timeChan chan time.Duration, th theme) (*segmentdisplay.SegmentDisplay, error) {
 	sd, err := segmentdisplay.New()
 	if err != nil {
 		return nil, err
@@ -470,14 +1293,14 @@ func newTimeSegmentDisplay(initMsg string, timeChan chan time.Duration) (*segmen
 	}*/
 
 	text := initMsg
-	updateChunks(sd, text, cell.ColorGreen)
+	updateChunks(sd, text, th.running)
 
 	go func(ch chan time.Duration) {
 		for {
 			t := <-ch
-			color := cell.ColorGreen
+			color := th.running
 			if t.Seconds() <= 3.0 {
-				color = cell.ColorRed
+				color = th.ending
 			}
 
 			updateChunks(sd, t.String(), color)
@@ -487,8 +1310,65 @@ func newTimeSegmentDisplay(initMsg string, timeChan chan time.Duration) (*segmen
 	return sd, nil
 }
 
+// newPlanText creates a new Text widget listing the sections of the
+// current round. The current section is highlighted and completed ones
+// are dimmed and checked (termdash cannot strike text through). The
+// list scrolls to keep the current section in view. The index of
+// a clicked section is sent on jump.
+func newPlanText(ch chan planView, jump chan<- int, th theme) (*planText, error) {
+	t, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+	p := &planText{Text: t, jump: jump, first: -1}
+
+	// Number of completed sections kept above the current one
+	const history = 2
+
+	go func() {
+		for {
+			v := <-ch
+			t.Reset()
+
+			write := func(txt string, color cell.Color) {
+				if err := t.Write(printable(txt), text.WriteCellOpts(cell.FgColor(color))); err != nil {
+					panic(err)
+				}
+			}
+
+			if v.sections == nil {
+				p.setLines(-1, 0)
+				write(v.raw, th.text)
+				continue
+			}
+
+			first := v.current - history
+			if first < 0 {
+				first = 0
+			}
+			p.setLines(first, len(v.sections))
+
+			for i := first; i < len(v.sections); i++ {
+				s := v.sections[i]
+				line := fmt.Sprintf("%s %v\n", s.Name, s.Duration)
+				switch {
+				case i < v.current:
+					write("✓ "+line, th.dim)
+				case i == v.current:
+					color, _ := th.sectionColors(s)
+					write("▶ "+line, color)
+				default:
+					write("  "+line, th.text)
+				}
+			}
+		}
+	}()
+
+	return p, nil
+}
+
 // newRollText creates a new Text widget that displays rolling text.
-func newRollText(ch chan string) (*text.Text, error) {
+func newRollText(ch chan string, th theme) (*text.Text, error) {
 	t, err := text.New(text.RollContent())
 	if err != nil {
 		return nil, err
@@ -498,7 +1378,7 @@ func newRollText(ch chan string) (*text.Text, error) {
 		for {
 			txt := <-ch
 			t.Reset()
-			if err := t.Write(txt, text.WriteCellOpts(cell.FgColor(cell.ColorWhite))); err != nil {
+			if err := t.Write(printable(txt), text.WriteCellOpts(cell.FgColor(th.text))); err != nil {
 				panic(err)
 			}
 		}
@@ -506,3 +1386,19 @@ func newRollText(ch chan string) (*text.Text, error) {
 
 	return t, nil
 }
+
+// printable makes txt writable by the Text widget which rejects
+// empty strings and space characters other than ' ' and '\n'
+func printable(txt string) string {
+	txt = strings.Map(func(r rune) rune {
+		if r != '\n' && (unicode.IsSpace(r) || unicode.IsControl(r)) {
+			return ' '
+		}
+		return r
+	}, txt)
+
+	if txt == "" {
+		return " "
+	}
+	return txt
+}
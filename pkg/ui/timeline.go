@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"github.com/Juli3nnicolas/bipper/pkg/document"
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/private/canvas"
+	"github.com/mum4k/termdash/terminal/terminalapi"
+	"github.com/mum4k/termdash/widgetapi"
+)
+
+// Timeline is a widget drawing the whole round as a horizontal bar,
+// one coloured segment per section proportional to its duration,
+// with a cursor at the current position
+type Timeline struct {
+	sections []document.Section
+	// total is the sum of the durations of the scheduled sections. It
+	// differs from Dynamic.Total as random durations are picked, the
+	// durations of until anchors computed and waiting sections added,
+	// so that the segments fill the whole bar.
+	total     time.Duration
+	remaining time.Duration
+	theme     theme
+
+	// mu protects the widget.
+	mu sync.Mutex
+}
+
+func newTimeline(th theme) *Timeline {
+	return &Timeline{theme: th}
+}
+
+// SetPlan sets the sections of the round, nil clears the timeline
+func (o *Timeline) SetPlan(sections []document.Section) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.sections = sections
+	o.total = 0
+	for _, s := range sections {
+		o.total += s.Duration
+	}
+	o.remaining = o.total
+}
+
+// SetRemaining moves the cursor, remaining is the time left in the round
+func (o *Timeline) SetRemaining(remaining time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.remaining = remaining
+}
+
+// Draw draws the bar on the first line and the cursor on the second one.
+// Elapsed time is drawn with full blocks, time left with shaded ones.
+// Implements widgetapi.Widget.Draw.
+func (o *Timeline) Draw(cvs *canvas.Canvas, meta *widgetapi.Meta) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.total <= 0 {
+		return nil
+	}
+
+	width := cvs.Area().Dx()
+	elapsed := o.total - o.remaining
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	cursor := int(int64(width-1) * int64(elapsed) / int64(o.total))
+
+	// Boundaries are computed from the cumulated durations
+	// so that rounding errors do not add up
+	var start time.Duration
+	for _, s := range o.sections {
+		from := int(int64(width) * int64(start) / int64(o.total))
+		start += s.Duration
+		to := int(int64(width) * int64(start) / int64(o.total))
+
+		color, _ := o.theme.sectionColors(s)
+		for x := from; x < to; x++ {
+			r := '▒'
+			if x < cursor {
+				r = '█'
+			}
+			if _, err := cvs.SetCell(image.Point{x, 0}, r, cell.FgColor(color)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cvs.Area().Dy() < 2 {
+		return cvs.SetCellOpts(image.Point{cursor, 0}, cell.BgColor(o.theme.cursor))
+	}
+	_, err := cvs.SetCell(image.Point{cursor, 1}, '▲', cell.FgColor(o.theme.cursor))
+	return err
+}
+
+// Keyboard is not used, the timeline does not register for keyboard events.
+func (o *Timeline) Keyboard(k *terminalapi.Keyboard) error {
+	return nil
+}
+
+// Mouse is not used, the timeline does not register for mouse events.
+func (o *Timeline) Mouse(m *terminalapi.Mouse) error {
+	return nil
+}
+
+// Options returns registration options for the widget.
+func (o *Timeline) Options() widgetapi.Options {
+	return widgetapi.Options{
+		MinimumSize: image.Point{1, 1},
+	}
+}